@@ -0,0 +1,821 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: api/currency/v1/currency.proto
+
+package currencyv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ConvertRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Amount        float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConvertRequest) Reset() {
+	*x = ConvertRequest{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConvertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertRequest) ProtoMessage() {}
+
+func (x *ConvertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertRequest.ProtoReflect.Descriptor instead.
+func (*ConvertRequest) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ConvertRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ConvertRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *ConvertRequest) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type ConvertResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	From            string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To              string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Amount          float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	ConvertedAmount float64                `protobuf:"fixed64,4,opt,name=converted_amount,json=convertedAmount,proto3" json:"converted_amount,omitempty"`
+	Rate            float64                `protobuf:"fixed64,5,opt,name=rate,proto3" json:"rate,omitempty"`
+	Version         uint64                 `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	AsOf            *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	Source          string                 `protobuf:"bytes,8,opt,name=source,proto3" json:"source,omitempty"`
+	// warning is set when the rates are older than the configured max age.
+	Warning       string `protobuf:"bytes,9,opt,name=warning,proto3" json:"warning,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConvertResponse) Reset() {
+	*x = ConvertResponse{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConvertResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertResponse) ProtoMessage() {}
+
+func (x *ConvertResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertResponse.ProtoReflect.Descriptor instead.
+func (*ConvertResponse) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ConvertResponse) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ConvertResponse) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *ConvertResponse) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *ConvertResponse) GetConvertedAmount() float64 {
+	if x != nil {
+		return x.ConvertedAmount
+	}
+	return 0
+}
+
+func (x *ConvertResponse) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *ConvertResponse) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ConvertResponse) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+func (x *ConvertResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ConvertResponse) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+type BatchConvertRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conversions   []*ConvertRequest      `protobuf:"bytes,1,rep,name=conversions,proto3" json:"conversions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchConvertRequest) Reset() {
+	*x = BatchConvertRequest{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchConvertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchConvertRequest) ProtoMessage() {}
+
+func (x *BatchConvertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchConvertRequest.ProtoReflect.Descriptor instead.
+func (*BatchConvertRequest) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchConvertRequest) GetConversions() []*ConvertRequest {
+	if x != nil {
+		return x.Conversions
+	}
+	return nil
+}
+
+type BatchConvertResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results are in request order.
+	Results       []*ConversionResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchConvertResponse) Reset() {
+	*x = BatchConvertResponse{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchConvertResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchConvertResponse) ProtoMessage() {}
+
+func (x *BatchConvertResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchConvertResponse.ProtoReflect.Descriptor instead.
+func (*BatchConvertResponse) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchConvertResponse) GetResults() []*ConversionResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ConversionResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*ConversionResult_Conversion
+	//	*ConversionResult_Error
+	Result        isConversionResult_Result `protobuf_oneof:"result"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConversionResult) Reset() {
+	*x = ConversionResult{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConversionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConversionResult) ProtoMessage() {}
+
+func (x *ConversionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConversionResult.ProtoReflect.Descriptor instead.
+func (*ConversionResult) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConversionResult) GetResult() isConversionResult_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *ConversionResult) GetConversion() *ConvertResponse {
+	if x != nil {
+		if x, ok := x.Result.(*ConversionResult_Conversion); ok {
+			return x.Conversion
+		}
+	}
+	return nil
+}
+
+func (x *ConversionResult) GetError() *ConversionError {
+	if x != nil {
+		if x, ok := x.Result.(*ConversionResult_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isConversionResult_Result interface {
+	isConversionResult_Result()
+}
+
+type ConversionResult_Conversion struct {
+	Conversion *ConvertResponse `protobuf:"bytes,1,opt,name=conversion,proto3,oneof"`
+}
+
+type ConversionResult_Error struct {
+	Error *ConversionError `protobuf:"bytes,2,opt,name=error,proto3,oneof"`
+}
+
+func (*ConversionResult_Conversion) isConversionResult_Result() {}
+
+func (*ConversionResult_Error) isConversionResult_Result() {}
+
+type ConversionError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// code is the stable error code the HTTP API reports for the same entry,
+	// e.g. UNSUPPORTED_CURRENCY.
+	Code          string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConversionError) Reset() {
+	*x = ConversionError{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConversionError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConversionError) ProtoMessage() {}
+
+func (x *ConversionError) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConversionError.ProtoReflect.Descriptor instead.
+func (*ConversionError) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ConversionError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ConversionError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetRatesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// symbols limits the table to these currencies; empty returns all.
+	Symbols       []string `protobuf:"bytes,1,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRatesRequest) Reset() {
+	*x = GetRatesRequest{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRatesRequest) ProtoMessage() {}
+
+func (x *GetRatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRatesRequest.ProtoReflect.Descriptor instead.
+func (*GetRatesRequest) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetRatesRequest) GetSymbols() []string {
+	if x != nil {
+		return x.Symbols
+	}
+	return nil
+}
+
+type Rates struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Base          string                 `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	Rates         map[string]float64     `protobuf:"bytes,2,rep,name=rates,proto3" json:"rates,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	Version       uint64                 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	AsOf          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	Source        string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+	Warning       string                 `protobuf:"bytes,6,opt,name=warning,proto3" json:"warning,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rates) Reset() {
+	*x = Rates{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rates) ProtoMessage() {}
+
+func (x *Rates) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rates.ProtoReflect.Descriptor instead.
+func (*Rates) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Rates) GetBase() string {
+	if x != nil {
+		return x.Base
+	}
+	return ""
+}
+
+func (x *Rates) GetRates() map[string]float64 {
+	if x != nil {
+		return x.Rates
+	}
+	return nil
+}
+
+func (x *Rates) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Rates) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+func (x *Rates) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Rates) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+type ListCurrenciesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCurrenciesRequest) Reset() {
+	*x = ListCurrenciesRequest{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCurrenciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCurrenciesRequest) ProtoMessage() {}
+
+func (x *ListCurrenciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCurrenciesRequest.ProtoReflect.Descriptor instead.
+func (*ListCurrenciesRequest) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{8}
+}
+
+type ListCurrenciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Base          string                 `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	Currencies    []string               `protobuf:"bytes,2,rep,name=currencies,proto3" json:"currencies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCurrenciesResponse) Reset() {
+	*x = ListCurrenciesResponse{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCurrenciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCurrenciesResponse) ProtoMessage() {}
+
+func (x *ListCurrenciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCurrenciesResponse.ProtoReflect.Descriptor instead.
+func (*ListCurrenciesResponse) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListCurrenciesResponse) GetBase() string {
+	if x != nil {
+		return x.Base
+	}
+	return ""
+}
+
+func (x *ListCurrenciesResponse) GetCurrencies() []string {
+	if x != nil {
+		return x.Currencies
+	}
+	return nil
+}
+
+type WatchRatesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// symbols limits every update to these currencies; empty sends all.
+	Symbols       []string `protobuf:"bytes,1,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRatesRequest) Reset() {
+	*x = WatchRatesRequest{}
+	mi := &file_api_currency_v1_currency_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRatesRequest) ProtoMessage() {}
+
+func (x *WatchRatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_currency_v1_currency_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRatesRequest.ProtoReflect.Descriptor instead.
+func (*WatchRatesRequest) Descriptor() ([]byte, []int) {
+	return file_api_currency_v1_currency_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchRatesRequest) GetSymbols() []string {
+	if x != nil {
+		return x.Symbols
+	}
+	return nil
+}
+
+var File_api_currency_v1_currency_proto protoreflect.FileDescriptor
+
+const file_api_currency_v1_currency_proto_rawDesc = "" +
+	"\n" +
+	"\x1eapi/currency/v1/currency.proto\x12\vcurrency.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"L\n" +
+	"\x0eConvertRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\"\x89\x02\n" +
+	"\x0fConvertResponse\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12)\n" +
+	"\x10converted_amount\x18\x04 \x01(\x01R\x0fconvertedAmount\x12\x12\n" +
+	"\x04rate\x18\x05 \x01(\x01R\x04rate\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x04R\aversion\x12/\n" +
+	"\x05as_of\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\x12\x16\n" +
+	"\x06source\x18\b \x01(\tR\x06source\x12\x18\n" +
+	"\awarning\x18\t \x01(\tR\awarning\"T\n" +
+	"\x13BatchConvertRequest\x12=\n" +
+	"\vconversions\x18\x01 \x03(\v2\x1b.currency.v1.ConvertRequestR\vconversions\"O\n" +
+	"\x14BatchConvertResponse\x127\n" +
+	"\aresults\x18\x01 \x03(\v2\x1d.currency.v1.ConversionResultR\aresults\"\x92\x01\n" +
+	"\x10ConversionResult\x12>\n" +
+	"\n" +
+	"conversion\x18\x01 \x01(\v2\x1c.currency.v1.ConvertResponseH\x00R\n" +
+	"conversion\x124\n" +
+	"\x05error\x18\x02 \x01(\v2\x1c.currency.v1.ConversionErrorH\x00R\x05errorB\b\n" +
+	"\x06result\"?\n" +
+	"\x0fConversionError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"+\n" +
+	"\x0fGetRatesRequest\x12\x18\n" +
+	"\asymbols\x18\x01 \x03(\tR\asymbols\"\x87\x02\n" +
+	"\x05Rates\x12\x12\n" +
+	"\x04base\x18\x01 \x01(\tR\x04base\x123\n" +
+	"\x05rates\x18\x02 \x03(\v2\x1d.currency.v1.Rates.RatesEntryR\x05rates\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x04R\aversion\x12/\n" +
+	"\x05as_of\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12\x18\n" +
+	"\awarning\x18\x06 \x01(\tR\awarning\x1a8\n" +
+	"\n" +
+	"RatesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\x17\n" +
+	"\x15ListCurrenciesRequest\"L\n" +
+	"\x16ListCurrenciesResponse\x12\x12\n" +
+	"\x04base\x18\x01 \x01(\tR\x04base\x12\x1e\n" +
+	"\n" +
+	"currencies\x18\x02 \x03(\tR\n" +
+	"currencies\"-\n" +
+	"\x11WatchRatesRequest\x12\x18\n" +
+	"\asymbols\x18\x01 \x03(\tR\asymbols2\x89\x03\n" +
+	"\x0fCurrencyService\x12D\n" +
+	"\aConvert\x12\x1b.currency.v1.ConvertRequest\x1a\x1c.currency.v1.ConvertResponse\x12S\n" +
+	"\fBatchConvert\x12 .currency.v1.BatchConvertRequest\x1a!.currency.v1.BatchConvertResponse\x12<\n" +
+	"\bGetRates\x12\x1c.currency.v1.GetRatesRequest\x1a\x12.currency.v1.Rates\x12Y\n" +
+	"\x0eListCurrencies\x12\".currency.v1.ListCurrenciesRequest\x1a#.currency.v1.ListCurrenciesResponse\x12B\n" +
+	"\n" +
+	"WatchRates\x12\x1e.currency.v1.WatchRatesRequest\x1a\x12.currency.v1.Rates0\x01B5Z3currency_go_microservice/api/currency/v1;currencyv1b\x06proto3"
+
+var (
+	file_api_currency_v1_currency_proto_rawDescOnce sync.Once
+	file_api_currency_v1_currency_proto_rawDescData []byte
+)
+
+func file_api_currency_v1_currency_proto_rawDescGZIP() []byte {
+	file_api_currency_v1_currency_proto_rawDescOnce.Do(func() {
+		file_api_currency_v1_currency_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_currency_v1_currency_proto_rawDesc), len(file_api_currency_v1_currency_proto_rawDesc)))
+	})
+	return file_api_currency_v1_currency_proto_rawDescData
+}
+
+var file_api_currency_v1_currency_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_api_currency_v1_currency_proto_goTypes = []any{
+	(*ConvertRequest)(nil),         // 0: currency.v1.ConvertRequest
+	(*ConvertResponse)(nil),        // 1: currency.v1.ConvertResponse
+	(*BatchConvertRequest)(nil),    // 2: currency.v1.BatchConvertRequest
+	(*BatchConvertResponse)(nil),   // 3: currency.v1.BatchConvertResponse
+	(*ConversionResult)(nil),       // 4: currency.v1.ConversionResult
+	(*ConversionError)(nil),        // 5: currency.v1.ConversionError
+	(*GetRatesRequest)(nil),        // 6: currency.v1.GetRatesRequest
+	(*Rates)(nil),                  // 7: currency.v1.Rates
+	(*ListCurrenciesRequest)(nil),  // 8: currency.v1.ListCurrenciesRequest
+	(*ListCurrenciesResponse)(nil), // 9: currency.v1.ListCurrenciesResponse
+	(*WatchRatesRequest)(nil),      // 10: currency.v1.WatchRatesRequest
+	nil,                            // 11: currency.v1.Rates.RatesEntry
+	(*timestamppb.Timestamp)(nil),  // 12: google.protobuf.Timestamp
+}
+var file_api_currency_v1_currency_proto_depIdxs = []int32{
+	12, // 0: currency.v1.ConvertResponse.as_of:type_name -> google.protobuf.Timestamp
+	0,  // 1: currency.v1.BatchConvertRequest.conversions:type_name -> currency.v1.ConvertRequest
+	4,  // 2: currency.v1.BatchConvertResponse.results:type_name -> currency.v1.ConversionResult
+	1,  // 3: currency.v1.ConversionResult.conversion:type_name -> currency.v1.ConvertResponse
+	5,  // 4: currency.v1.ConversionResult.error:type_name -> currency.v1.ConversionError
+	11, // 5: currency.v1.Rates.rates:type_name -> currency.v1.Rates.RatesEntry
+	12, // 6: currency.v1.Rates.as_of:type_name -> google.protobuf.Timestamp
+	0,  // 7: currency.v1.CurrencyService.Convert:input_type -> currency.v1.ConvertRequest
+	2,  // 8: currency.v1.CurrencyService.BatchConvert:input_type -> currency.v1.BatchConvertRequest
+	6,  // 9: currency.v1.CurrencyService.GetRates:input_type -> currency.v1.GetRatesRequest
+	8,  // 10: currency.v1.CurrencyService.ListCurrencies:input_type -> currency.v1.ListCurrenciesRequest
+	10, // 11: currency.v1.CurrencyService.WatchRates:input_type -> currency.v1.WatchRatesRequest
+	1,  // 12: currency.v1.CurrencyService.Convert:output_type -> currency.v1.ConvertResponse
+	3,  // 13: currency.v1.CurrencyService.BatchConvert:output_type -> currency.v1.BatchConvertResponse
+	7,  // 14: currency.v1.CurrencyService.GetRates:output_type -> currency.v1.Rates
+	9,  // 15: currency.v1.CurrencyService.ListCurrencies:output_type -> currency.v1.ListCurrenciesResponse
+	7,  // 16: currency.v1.CurrencyService.WatchRates:output_type -> currency.v1.Rates
+	12, // [12:17] is the sub-list for method output_type
+	7,  // [7:12] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_api_currency_v1_currency_proto_init() }
+func file_api_currency_v1_currency_proto_init() {
+	if File_api_currency_v1_currency_proto != nil {
+		return
+	}
+	file_api_currency_v1_currency_proto_msgTypes[4].OneofWrappers = []any{
+		(*ConversionResult_Conversion)(nil),
+		(*ConversionResult_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_currency_v1_currency_proto_rawDesc), len(file_api_currency_v1_currency_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_currency_v1_currency_proto_goTypes,
+		DependencyIndexes: file_api_currency_v1_currency_proto_depIdxs,
+		MessageInfos:      file_api_currency_v1_currency_proto_msgTypes,
+	}.Build()
+	File_api_currency_v1_currency_proto = out.File
+	file_api_currency_v1_currency_proto_goTypes = nil
+	file_api_currency_v1_currency_proto_depIdxs = nil
+}
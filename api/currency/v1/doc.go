@@ -0,0 +1,5 @@
+// Package currencyv1 is the gRPC API of the currency exchange service,
+// generated from currency.proto
+package currencyv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/currency/v1/currency.proto
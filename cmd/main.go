@@ -1,29 +1,310 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/config"
+	"currency_go_microservice/internal/cors"
+	"currency_go_microservice/internal/grpcapi"
+	"currency_go_microservice/internal/logging"
+	"currency_go_microservice/internal/metrics"
+	"currency_go_microservice/internal/provider"
+	"currency_go_microservice/internal/ratelimit"
+	"currency_go_microservice/internal/router"
+	"currency_go_microservice/internal/server"
 	"currency_go_microservice/internal/service"
+	"currency_go_microservice/internal/tracing"
 )
 
 func main() {
-	// Create currency service instance
-	currencyService := service.NewCurrencyService()
+	// "apikey generate" prints a new API key and its hash and exits
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "apikey" && args[1] == "generate" {
+		key, hash, err := auth.GenerateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("key:  %s\nhash: %s\n", key, hash)
+		return
+	}
+	// "config print" shows the effective configuration and exits
+	printConfig := len(args) >= 2 && args[0] == "config" && args[1] == "print"
+	if printConfig {
+		args = args[2:]
+	}
 
-	// Set up routes
-	http.HandleFunc("/exchange", currencyService.ExchangeHandler)
-	http.HandleFunc("/health", currencyService.HealthHandler)
-	http.HandleFunc("/rates", currencyService.RatesHandler)
+	cfg, err := config.Load(args, os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	if printConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	}, os.Stdout)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	// Stop on SIGINT/SIGTERM; the server drains before background work stops
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Create currency service instance, refreshing rates from upstream
+	// providers when configured
+	registry := metrics.NewRegistry()
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+	currencyService := service.NewCurrencyService(append(serviceOptions(cfg), service.WithMetrics(registry))...)
+
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	if len(cfg.Rates.Providers) > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			currencyService.RunRefresher(refreshCtx, cfg.Rates.RefreshInterval)
+		}()
+	}
+
+	// Set up routes. Middleware runs outermost first: request IDs and the
+	// access log see every request, CORS answers preflights before routing
+	// and authentication, and recovery sits inside metrics and tracing so a
+	// panic is still recorded as a 500.
+	rt := router.New(service.WriteError)
+	rt.Use(
+		logging.RequestIDs,
+		logging.AccessLog(logger),
+		tracing.Middleware,
+		httpMetrics.Middleware,
+	)
+	if cfg.CORS.Enabled() {
+		rt.Use(cors.Middleware(corsConfig(cfg.CORS)))
+	}
+	rt.Use(router.Recover(service.WriteError))
+	authenticators, err := buildAuthenticators(cfg.Auth)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	routeOpts := service.RouteOptions{
+		SnapshotAdmin: cfg.Features.SnapshotAdmin,
+		Legacy: service.LegacyRoutes{
+			Disabled:     !cfg.API.LegacyRoutes,
+			DeprecatedAt: cfg.API.DeprecatedAt,
+			Sunset:       cfg.API.Sunset,
+		},
+	}
+	if len(authenticators) > 0 {
+		routeOpts.Authenticate = auth.Middleware(service.WriteError, authenticators...)
+	} else {
+		logger.Warn("client authentication is disabled")
+	}
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter, err = buildLimiter(cfg.RateLimit)
+		if err != nil {
+			log.Fatalf("invalid configuration: %v", err)
+		}
+		routeOpts.RateLimit = limiter.RateLimit(service.WriteError)
+		routeOpts.Quota = limiter.Quota(service.WriteError)
+	}
+	currencyService.RegisterRoutes(rt, routeOpts)
+	rt.Handle(http.MethodGet, "/metrics", registry.Handler())
 
 	// Start server
-	port := ":8080"
-	fmt.Printf("Currency Exchange Service starting on port %s\n", port)
-	fmt.Println("Available endpoints:")
-	fmt.Println("  GET /exchange?from=USD&to=EUR&amount=100")
-	fmt.Println("  GET /health")
-	fmt.Println("  GET /rates")
-
-	log.Fatal(http.ListenAndServe(port, nil))
+	var endpoints []string
+	for _, route := range rt.Routes() {
+		endpoints = append(endpoints, route.Method+" "+route.Pattern)
+	}
+	srvCfg := cfg.ServerSettings()
+	srv := server.New(srvCfg, rt)
+	srv.RegisterOnShutdown(currencyService.StopStreams)
+	if srvCfg.TLS.Enabled() {
+		if err := server.ConfigureTLS(refreshCtx, srv, srvCfg.TLS); err != nil {
+			log.Fatalf("invalid TLS configuration: %v", err)
+		}
+	}
+	logger.Info("currency exchange service starting", "addr", cfg.Server.Addr, "tls", srvCfg.TLS.Enabled(), "endpoints", endpoints)
+
+	// The gRPC API shares the service, TLS settings, authenticators and
+	// limiter; a failure of either server shuts both down
+	var grpcErr error
+	if cfg.GRPC.Addr != "" {
+		ln, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			log.Fatalf("failed to listen for gRPC: %v", err)
+		}
+		grpcServer := grpcapi.New(currencyService, grpcapi.Options{
+			TLSConfig:      srv.TLSConfig,
+			Authenticators: authenticators,
+			Limiter:        limiter,
+			Logger:         logger,
+		})
+		logger.Info("gRPC server starting", "addr", cfg.GRPC.Addr)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			if grpcErr = grpcapi.Serve(ctx, grpcServer, ln, srvCfg.ShutdownTimeout); grpcErr != nil {
+				stop()
+			}
+		}()
+	}
+
+	err = server.Run(ctx, srv, srvCfg.ShutdownTimeout)
+	stop()
+	stopRefresh()
+	background.Wait()
+	if err == nil {
+		err = grpcErr
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("failed to flush traces", "error", err)
+	}
+	cancelFlush()
+	if err != nil {
+		logger.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("shutdown complete")
+}
+
+// serviceOptions translates the configuration into service options
+func serviceOptions(cfg config.Config) []service.Option {
+	opts := []service.Option{
+		service.WithSnapshotStore(service.NewMemorySnapshotStore(cfg.Rates.SnapshotHistory)),
+		service.WithMaxAge(cfg.Rates.MaxAge, service.StalePolicy(cfg.Rates.StalePolicy)),
+	}
+
+	if cfg.Features.AnomalyGuard {
+		guard := service.DefaultAnomalyGuard()
+		guard.MaxChangePercent = cfg.Rates.MaxChangePercent
+		opts = append(opts, service.WithAnomalyGuard(guard))
+	} else {
+		opts = append(opts, service.WithAnomalyGuard(nil))
+	}
+
+	if p := buildProvider(cfg.Rates); p != nil {
+		opts = append(opts, service.WithRateProvider(p))
+	}
+
+	// Browsers send WebSocket handshakes cross-origin without a preflight,
+	// so the CORS origin list is enforced by the handler itself
+	stream := service.StreamOptions{
+		Heartbeat:    cfg.Stream.Heartbeat,
+		WriteTimeout: cfg.Stream.WriteTimeout,
+		MaxClients:   cfg.Stream.MaxClients,
+	}
+	if cfg.CORS.Enabled() {
+		stream.CheckOrigin = cors.OriginChecker(corsConfig(cfg.CORS))
+	}
+	return append(opts, service.WithStreamOptions(stream))
+}
+
+// corsConfig translates the CORS settings
+func corsConfig(cfg config.CORSConfig) cors.Config {
+	return cors.Config{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+}
+
+// buildProvider combines the configured providers into a consensus
+// aggregator or, in fallback mode, a fallback chain behind circuit breakers
+func buildProvider(cfg config.RatesConfig) provider.RateProvider {
+	if len(cfg.Providers) == 0 {
+		return nil
+	}
+
+	client := &http.Client{Timeout: 2 * cfg.ProviderTimeout, Transport: tracing.Transport(nil)}
+	providers := make([]provider.RateProvider, len(cfg.Providers))
+	for i, p := range cfg.Providers {
+		providers[i] = provider.NewHTTPProvider(p.Name, p.URL, client)
+	}
+
+	if cfg.Mode == "fallback" {
+		return provider.NewFallback(provider.DefaultBreakerConfig(), cfg.ProviderTimeout, providers...)
+	}
+
+	aggregator := provider.NewAggregator(cfg.ProviderTimeout, providers...)
+	aggregator.Method = provider.ConsensusMethod(cfg.Consensus)
+	return aggregator
+}
+
+// buildAuthenticators returns the authenticators for the configured
+// credential sources, none when authentication is disabled
+func buildAuthenticators(cfg config.AuthConfig) ([]auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+	if cfg.APIKeysFile != "" {
+		keys, err := auth.LoadKeyFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(keys))
+	}
+	if cfg.JWT.Enabled() {
+		keySet := auth.NewFileKeySet(cfg.JWT.JWKSFile, cfg.JWT.JWKSRefresh)
+		if cfg.JWT.JWKSURL != "" {
+			client := &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(nil)}
+			keySet = auth.NewRemoteKeySet(cfg.JWT.JWKSURL, client, cfg.JWT.JWKSRefresh)
+		}
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(auth.JWTConfig{
+			Issuer:   cfg.JWT.Issuer,
+			Audience: cfg.JWT.Audience,
+			ScopeMap: cfg.JWT.Scopes,
+			Leeway:   cfg.JWT.Leeway,
+		}, keySet))
+	}
+	if len(cfg.ClientCerts) > 0 {
+		authenticators = append(authenticators, auth.NewClientCertAuthenticator(cfg.ClientCerts))
+	}
+	return authenticators, nil
+}
+
+// buildLimiter creates an in-memory rate limiter for the configured plans
+func buildLimiter(cfg config.RateLimitConfig) (*ratelimit.Limiter, error) {
+	plans := make(map[string]ratelimit.Plan, len(cfg.Plans))
+	for name, p := range cfg.Plans {
+		plans[name] = ratelimit.Plan{RequestsPerSecond: p.RequestsPerSecond, Burst: p.Burst, DailyQuota: p.DailyQuota}
+	}
+	return ratelimit.New(ratelimit.Config{
+		Plans:         plans,
+		Clients:       cfg.Clients,
+		DefaultPlan:   cfg.DefaultPlan,
+		AnonymousPlan: cfg.AnonymousPlan,
+	}, ratelimit.NewMemoryStore())
 }
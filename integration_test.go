@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
@@ -85,19 +88,10 @@ func TestCurrencyExchangeServiceIntegration(t *testing.T) {
 	client := newLocalClient()
 
 	// Optionally start a local server when running outside CI
-	var cmd *exec.Cmd
+	var local *localServer
 	if os.Getenv("START_LOCAL") == "1" {
-		cmd = exec.Command("go", "run", "cmd/main.go")
-		cmd.Dir = "."
-		if err := cmd.Start(); err != nil {
-			t.Fatalf("Failed to start local server: %v", err)
-		}
-		defer func() {
-			if cmd.Process != nil {
-				_ = cmd.Process.Signal(syscall.SIGTERM)
-				_, _ = cmd.Process.Wait()
-			}
-		}()
+		local = startLocalServer(t, baseURL)
+		defer local.stop()
 	}
 
 	// Wait for the service to be ready
@@ -335,6 +329,82 @@ func TestCurrencyExchangeServiceIntegration(t *testing.T) {
 			t.Errorf("Expected specific error message, got '%s'", errorResp.Error)
 		}
 	})
+
+	if local != nil {
+		t.Run("Graceful Shutdown Integration", func(t *testing.T) {
+			if err := local.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+				t.Fatalf("Failed to send SIGTERM: %v", err)
+			}
+
+			select {
+			case <-local.done:
+			case <-time.After(30 * time.Second):
+				t.Fatalf("Server did not shut down within 30s. Output:\n%s", local.output.String())
+			}
+
+			if !local.cmd.ProcessState.Success() {
+				t.Errorf("Expected clean exit, got %v. Output:\n%s", local.cmd.ProcessState, local.output.String())
+			}
+			if !strings.Contains(local.output.String(), "shutdown complete") {
+				t.Errorf("Expected shutdown to complete. Output:\n%s", local.output.String())
+			}
+			if _, err := client.Get(baseURL + "/livez"); err == nil {
+				t.Errorf("Expected server to stop accepting connections after shutdown")
+			}
+		})
+	}
+}
+
+// localServer is a service binary started by the START_LOCAL path
+type localServer struct {
+	cmd    *exec.Cmd
+	output bytes.Buffer
+	done   chan struct{}
+}
+
+// startLocalServer builds the service and runs it listening on baseURL's
+// host and port. The binary is run directly rather than via "go run" so
+// that signals reach the server process.
+func startLocalServer(t *testing.T, baseURL string) *localServer {
+	binary := filepath.Join(t.TempDir(), "currency-service")
+	if out, err := exec.Command("go", "build", "-o", binary, "./cmd").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build local server: %v\n%s", err, out)
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		t.Fatalf("Invalid BASE_URL %q: %v", baseURL, err)
+	}
+
+	s := &localServer{done: make(chan struct{})}
+	s.cmd = exec.Command(binary)
+	s.cmd.Env = append(os.Environ(), "LISTEN_ADDR="+u.Host)
+	s.cmd.Stdout = &s.output
+	s.cmd.Stderr = &s.output
+	if err := s.cmd.Start(); err != nil {
+		t.Fatalf("Failed to start local server: %v", err)
+	}
+	go func() {
+		_ = s.cmd.Wait()
+		close(s.done)
+	}()
+	return s
+}
+
+// stop terminates the server if it is still running
+func (s *localServer) stop() {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	_ = s.cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-s.done:
+	case <-time.After(30 * time.Second):
+		_ = s.cmd.Process.Kill()
+		<-s.done
+	}
 }
 
 // Benchmark tests for performance using service directly
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKeyHeader carries the API key on requests
+const APIKeyHeader = "X-API-Key"
+
+// hashPrefix marks the hash algorithm of stored keys
+const hashPrefix = "sha256:"
+
+// ErrKeyNotFound is returned by a KeyStore for unknown key hashes
+var ErrKeyNotFound = errors.New("api key not found")
+
+// APIKey is a stored API key. Only the hash of the key is kept.
+type APIKey struct {
+	ClientID string   `yaml:"client_id" json:"client_id"`
+	Hash     string   `yaml:"hash" json:"hash"`
+	Scopes   []string `yaml:"scopes" json:"scopes"`
+	Disabled bool     `yaml:"disabled" json:"disabled"`
+}
+
+// KeyStore looks API keys up by hash
+type KeyStore interface {
+	Lookup(ctx context.Context, hash string) (*APIKey, error)
+}
+
+// HashKey returns the at-rest form of an API key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random API key and its hash
+func GenerateKey() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = "cx_" + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashKey(key), nil
+}
+
+// MemoryKeyStore is a KeyStore held in memory
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]APIKey
+}
+
+// NewMemoryKeyStore creates a store holding keys. Every key needs a client
+// ID and a sha256 hash, and hashes must be unique.
+func NewMemoryKeyStore(keys ...APIKey) (*MemoryKeyStore, error) {
+	s := &MemoryKeyStore{keys: make(map[string]APIKey, len(keys))}
+	for i, k := range keys {
+		k.Hash = strings.ToLower(k.Hash)
+		if k.ClientID == "" {
+			return nil, fmt.Errorf("api key %d has no client_id", i+1)
+		}
+		digest, ok := strings.CutPrefix(k.Hash, hashPrefix)
+		if _, err := hex.DecodeString(digest); !ok || err != nil || len(digest) != 2*sha256.Size {
+			return nil, fmt.Errorf("api key for %s needs a hash of the form sha256:<64 hex digits>", k.ClientID)
+		}
+		if _, exists := s.keys[k.Hash]; exists {
+			return nil, fmt.Errorf("api key for %s duplicates another key", k.ClientID)
+		}
+		s.keys[k.Hash] = k
+	}
+	return s, nil
+}
+
+// LoadKeyFile reads a YAML or JSON file with a top-level "keys" list
+func LoadKeyFile(path string) (*MemoryKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Keys []APIKey `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	store, err := NewMemoryKeyStore(file.Keys...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Lookup returns the key with the given hash
+func (s *MemoryKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[hash]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return &k, nil
+}
+
+// APIKeyAuthenticator authenticates requests by their X-API-Key header
+type APIKeyAuthenticator struct {
+	store KeyStore
+}
+
+// NewAPIKeyAuthenticator checks keys against store
+func NewAPIKeyAuthenticator(store KeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{store: store}
+}
+
+// Authenticate hashes the presented key and looks it up in the store
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	k, err := a.store.Lookup(r.Context(), HashKey(key))
+	if errors.Is(err, ErrKeyNotFound) || (err == nil && k.Disabled) {
+		return nil, fmt.Errorf("%w: unknown or disabled API key", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("api key lookup failed: %w", err)
+	}
+	return &Identity{ClientID: k.ClientID, Scopes: k.Scopes, Method: "api_key"}, nil
+}
+
+// Challenge names the API key scheme
+func (a *APIKeyAuthenticator) Challenge() string {
+	return `ApiKey header="` + APIKeyHeader + `"`
+}
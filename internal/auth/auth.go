@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"currency_go_microservice/internal/router"
+)
+
+// Scopes checked by the service routes
+const (
+	ScopeRead  = "rates:read"
+	ScopeAdmin = "admin"
+)
+
+var (
+	// ErrNoCredentials means the request carries no credentials an
+	// Authenticator understands
+	ErrNoCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials means the credentials were present but rejected
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	ClientID string
+	Scopes   []string
+	Method   string
+}
+
+// HasScope reports whether the identity was granted scope
+func (id *Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator verifies one kind of request credentials
+type Authenticator interface {
+	// Authenticate returns the caller's identity. It returns
+	// ErrNoCredentials when the request has none of its kind, so the next
+	// authenticator can be tried.
+	Authenticate(r *http.Request) (*Identity, error)
+	// Challenge is the WWW-Authenticate value sent with 401 responses
+	Challenge() string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx, if any
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// Middleware authenticates every request with the first authenticator that
+// finds credentials and stores the identity in the request context.
+// Requests without valid credentials get 401 written with writeError, and
+// 503 when credentials cannot be checked.
+func Middleware(writeError router.ErrorWriter, authenticators ...Authenticator) router.Middleware {
+	var challenges []string
+	for _, a := range authenticators {
+		if c := a.Challenge(); c != "" {
+			challenges = append(challenges, c)
+		}
+	}
+	challenge := strings.Join(challenges, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := Authenticate(r, authenticators...)
+			switch {
+			case errors.Is(err, ErrNoCredentials):
+				setChallenge(w, challenge)
+				writeError(w, r, http.StatusUnauthorized, "Missing credentials")
+			case errors.Is(err, ErrInvalidCredentials):
+				setChallenge(w, challenge)
+				writeError(w, r, http.StatusUnauthorized, err.Error())
+			case err != nil:
+				slog.ErrorContext(r.Context(), "authentication failed", "error", err)
+				writeError(w, r, http.StatusServiceUnavailable, "Authentication is temporarily unavailable")
+			default:
+				next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+			}
+		})
+	}
+}
+
+// Authenticate returns the identity found by the first authenticator that
+// finds credentials on r. It returns ErrNoCredentials when none does, an
+// error wrapping ErrInvalidCredentials for rejected credentials, and any
+// other error when credentials could not be checked.
+func Authenticate(r *http.Request, authenticators ...Authenticator) (*Identity, error) {
+	for _, a := range authenticators {
+		id, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+func setChallenge(w http.ResponseWriter, challenge string) {
+	if challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+}
+
+// RequireScope rejects requests whose identity lacks scope with 403. It
+// must run after Middleware.
+func RequireScope(writeError router.ErrorWriter, scope string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := FromContext(r.Context())
+			if !ok {
+				writeError(w, r, http.StatusUnauthorized, "Missing credentials")
+				return
+			}
+			if !id.HasScope(scope) {
+				writeError(w, r, http.StatusForbidden, "Client "+id.ClientID+" lacks the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// failingStore is a KeyStore whose backend is down
+type failingStore struct{}
+
+func (failingStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	store, err := NewMemoryKeyStore(
+		APIKey{ClientID: "billing", Hash: HashKey("reader-key"), Scopes: []string{ScopeRead}},
+		APIKey{ClientID: "ops", Hash: HashKey("admin-key"), Scopes: []string{ScopeRead, ScopeAdmin}},
+		APIKey{ClientID: "retired", Hash: HashKey("old-key"), Scopes: []string{ScopeRead}, Disabled: true},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		id, _ := FromContext(r.Context())
+		json.NewEncoder(w).Encode(map[string]string{"client": id.ClientID})
+	}
+	admin := Middleware(writeError, NewAPIKeyAuthenticator(store))(RequireScope(writeError, ScopeAdmin)(http.HandlerFunc(handler)))
+	broken := Middleware(writeError, NewAPIKeyAuthenticator(failingStore{}))(http.HandlerFunc(handler))
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		key            string
+		expectedStatus int
+		expectedClient string
+	}{
+		{"Missing key", admin, "", http.StatusUnauthorized, ""},
+		{"Unknown key", admin, "guess", http.StatusUnauthorized, ""},
+		{"Disabled key", admin, "old-key", http.StatusUnauthorized, ""},
+		{"Missing scope", admin, "reader-key", http.StatusForbidden, ""},
+		{"Admin key", admin, "admin-key", http.StatusOK, "ops"},
+		{"Store unavailable", broken, "admin-key", http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/snapshots", nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Could not parse JSON response: %v", err)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if body["client"] != tt.expectedClient {
+					t.Errorf("Expected client %q, got %q", tt.expectedClient, body["client"])
+				}
+				return
+			}
+			if body["error"] == "" {
+				t.Errorf("Expected error message")
+			}
+			if rr.Code == http.StatusUnauthorized && !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "ApiKey") {
+				t.Errorf("Expected ApiKey challenge, got %q", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	key, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(hash, key) || HashKey(key) != hash {
+		t.Fatalf("Expected hash to be derived from, but not contain, the key")
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{"Valid file", "keys:\n  - client_id: billing\n    hash: " + hash + "\n    scopes: [rates:read]\n", ""},
+		{"Plain text key", "keys:\n  - client_id: billing\n    hash: " + key + "\n", "sha256:<64 hex digits>"},
+		{"Missing client", "keys:\n  - hash: " + hash + "\n", "no client_id"},
+		{"Duplicate hash", "keys:\n  - {client_id: a, hash: " + hash + "}\n  - {client_id: b, hash: " + hash + "}\n", "duplicates"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			store, err := LoadKeyFile(path)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error mentioning %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			k, err := store.Lookup(context.Background(), HashKey(key))
+			if err != nil || k.ClientID != "billing" {
+				t.Errorf("Expected billing key, got %+v, %v", k, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+)
+
+// ClientCertAuthenticator identifies callers by the TLS client certificate
+// verified during the mutual TLS handshake
+type ClientCertAuthenticator struct {
+	subjects map[string][]string
+}
+
+// NewClientCertAuthenticator maps certificate subjects to scopes. A key is
+// either a full subject such as "CN=billing,O=Acme" or a bare common name;
+// the full subject is tried first. The client ID is the common name.
+func NewClientCertAuthenticator(subjects map[string][]string) *ClientCertAuthenticator {
+	return &ClientCertAuthenticator{subjects: subjects}
+}
+
+// Authenticate implements Authenticator. Chain verification is left to the
+// TLS stack, so only certificates it verified are considered.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	scopes, ok := a.lookup(cert)
+	if !ok {
+		return nil, fmt.Errorf("%w: certificate subject %q is not authorised", ErrInvalidCredentials, cert.Subject.String())
+	}
+	clientID := cert.Subject.CommonName
+	if clientID == "" {
+		clientID = cert.Subject.String()
+	}
+	return &Identity{ClientID: clientID, Scopes: scopes, Method: "client_cert"}, nil
+}
+
+func (a *ClientCertAuthenticator) lookup(cert *x509.Certificate) ([]string, bool) {
+	if scopes, ok := a.subjects[cert.Subject.String()]; ok {
+		return scopes, true
+	}
+	if cn := cert.Subject.CommonName; cn != "" {
+		scopes, ok := a.subjects[cn]
+		return scopes, ok
+	}
+	return nil, false
+}
+
+// Challenge implements Authenticator. HTTP has no challenge for client
+// certificates, so none is advertised.
+func (a *ClientCertAuthenticator) Challenge() string { return "" }
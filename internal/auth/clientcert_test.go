@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCertAuthenticator(t *testing.T) {
+	a := NewClientCertAuthenticator(map[string][]string{
+		"CN=ops,O=Acme": {ScopeRead, ScopeAdmin},
+		"billing":       {ScopeRead},
+	})
+
+	withCert := func(subject pkix.Name) *http.Request {
+		req := httptest.NewRequest("GET", "/rates", nil)
+		cert := &x509.Certificate{Subject: subject}
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return req
+	}
+	unverified := httptest.NewRequest("GET", "/rates", nil)
+	unverified.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "billing"}}}}
+
+	tests := []struct {
+		name           string
+		req            *http.Request
+		expectedErr    error
+		expectedClient string
+		expectedAdmin  bool
+	}{
+		{"Plain HTTP", httptest.NewRequest("GET", "/rates", nil), ErrNoCredentials, "", false},
+		{"Unverified certificate", unverified, ErrNoCredentials, "", false},
+		{"Common name", withCert(pkix.Name{CommonName: "billing", Organization: []string{"Acme"}}), nil, "billing", false},
+		{"Full subject", withCert(pkix.Name{CommonName: "ops", Organization: []string{"Acme"}}), nil, "ops", true},
+		{"Full subject mismatch", withCert(pkix.Name{CommonName: "ops", Organization: []string{"Other"}}), ErrInvalidCredentials, "", false},
+		{"Unknown subject", withCert(pkix.Name{CommonName: "stranger"}), ErrInvalidCredentials, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.Authenticate(tt.req)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if id.ClientID != tt.expectedClient || id.Method != "client_cert" {
+				t.Errorf("Expected client %q via client_cert, got %q via %q", tt.expectedClient, id.ClientID, id.Method)
+			}
+			if id.HasScope(ScopeAdmin) != tt.expectedAdmin {
+				t.Errorf("Expected admin scope %v, got %v", tt.expectedAdmin, id.HasScope(ScopeAdmin))
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minForcedRefresh limits how often an unknown key ID triggers a reload, so
+// tokens with made-up key IDs cannot hammer the JWKS endpoint
+const minForcedRefresh = time.Minute
+
+// reloadTimeout bounds a JWKS fetch, which does not end with the request
+// that started it
+const reloadTimeout = 10 * time.Second
+
+// KeySet is a cached JSON Web Key Set. Keys are reloaded after the refresh
+// interval and, at most once a minute, when a token names an unknown key,
+// which picks up rotated signing keys. Concurrent requests share one
+// reload, which runs without holding the lock.
+type KeySet struct {
+	load    func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+	inflight *reloadCall
+}
+
+// reloadCall is a reload in progress; err is set before done is closed
+type reloadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteKeySet loads keys from a JWKS URL. A nil client uses
+// http.DefaultClient.
+func NewRemoteKeySet(url string, client *http.Client, refresh time.Duration) *KeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newKeySet(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jwks %s returned status %d", url, resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}, refresh)
+}
+
+// NewFileKeySet loads keys from a local JWKS file
+func NewFileKeySet(path string, refresh time.Duration) *KeySet {
+	return newKeySet(func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, refresh)
+}
+
+func newKeySet(load func(context.Context) ([]byte, error), refresh time.Duration) *KeySet {
+	return &KeySet{load: load, refresh: refresh, now: time.Now}
+}
+
+// Key returns the public key with the given key ID. Key IDs that are not
+// in the set, even after a reload, are invalid credentials; an error of
+// another kind means no keys could be loaded at all.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	keys, loadedAt := s.snapshot()
+	if keys == nil || s.now().Sub(loadedAt) >= s.refresh {
+		err := s.reload(ctx)
+		if keys, loadedAt = s.snapshot(); keys == nil {
+			return nil, err
+		}
+	}
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if s.now().Sub(loadedAt) >= minForcedRefresh {
+		// A failed reload leaves the cached keys, which lack kid
+		s.reload(ctx)
+		keys, _ = s.snapshot()
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidCredentials, kid)
+}
+
+// snapshot returns the cached keys and when they were loaded
+func (s *KeySet) snapshot() (map[string]crypto.PublicKey, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys, s.loadedAt
+}
+
+// reload fetches the key set, joining a reload already in progress, and
+// waits for it or for ctx. The fetch itself is not cancelled with ctx.
+func (s *KeySet) reload(ctx context.Context) error {
+	s.mu.Lock()
+	call := s.inflight
+	if call == nil {
+		call = &reloadCall{done: make(chan struct{})}
+		s.inflight = call
+		go s.fetch(context.WithoutCancel(ctx), call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fetch loads and installs the key set; on failure the previous keys stay
+// in use
+func (s *KeySet) fetch(ctx context.Context, call *reloadCall) {
+	ctx, cancel := context.WithTimeout(ctx, reloadTimeout)
+	defer cancel()
+
+	data, err := s.load(ctx)
+	var keys map[string]crypto.PublicKey
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.loadedAt = s.now()
+	s.inflight = nil
+	cached := len(s.keys)
+	s.mu.Unlock()
+
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load JWKS", "error", err, "cached_keys", cached)
+		err = fmt.Errorf("failed to load JWKS: %w", err)
+	}
+	call.err = err
+	close(call.done)
+}
+
+// jwk is the subset of RFC 7517 fields used for RSA and P-256 keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes RSA and P-256 keys; other key types are skipped
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
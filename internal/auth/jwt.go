@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTConfig describes which bearer tokens are accepted
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	// ScopeMap maps scope claim values to service scopes. Values missing
+	// from the map are ignored; a nil map uses the claim values as they are.
+	ScopeMap map[string][]string
+	// Leeway is the clock skew tolerated on exp and nbf
+	Leeway time.Duration
+}
+
+// JWTAuthenticator validates RS256 and ES256 bearer tokens against a KeySet
+type JWTAuthenticator struct {
+	cfg  JWTConfig
+	keys *KeySet
+	now  func() time.Time
+}
+
+// NewJWTAuthenticator checks tokens signed by keys in keys
+func NewJWTAuthenticator(cfg JWTConfig, keys *KeySet) *JWTAuthenticator {
+	return &JWTAuthenticator{cfg: cfg, keys: keys, now: time.Now}
+}
+
+// claims holds the registered claims checked and the ones mapped to an
+// Identity. Audience and scope may be a string or a list.
+type claims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  stringList      `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	ClientID  string          `json:"client_id"`
+	AZP       string          `json:"azp"`
+	Scope     json.RawMessage `json:"scope"`
+	SCP       json.RawMessage `json:"scp"`
+}
+
+// stringList decodes a JSON string or array of strings
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*l = strings.Fields(one)
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// Authenticate verifies the Authorization: Bearer token
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	c, err := a.verify(r, strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := c.ClientID
+	if clientID == "" {
+		clientID = c.AZP
+	}
+	if clientID == "" {
+		clientID = c.Subject
+	}
+	return &Identity{ClientID: clientID, Scopes: a.mapScopes(c), Method: "jwt"}, nil
+}
+
+// Challenge names the bearer scheme
+func (a *JWTAuthenticator) Challenge() string {
+	return `Bearer realm="currency-exchange"`
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidCredentials}, args...)...)
+}
+
+func (a *JWTAuthenticator) verify(r *http.Request, token string) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalid("malformed token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed token signature")
+	}
+
+	key, err := a.keys.Key(r.Context(), header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(header.Alg, key, digest[:], sig) {
+		return nil, invalid("bad signature or unsupported algorithm %q", header.Alg)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, invalid("malformed token claims")
+	}
+	now := a.now()
+	if c.ExpiresAt == nil {
+		return nil, invalid("token has no expiry")
+	}
+	if now.After(time.Unix(*c.ExpiresAt, 0).Add(a.cfg.Leeway)) {
+		return nil, invalid("token expired")
+	}
+	if c.NotBefore != nil && now.Add(a.cfg.Leeway).Before(time.Unix(*c.NotBefore, 0)) {
+		return nil, invalid("token not valid yet")
+	}
+	if a.cfg.Issuer != "" && c.Issuer != a.cfg.Issuer {
+		return nil, invalid("unexpected issuer %q", c.Issuer)
+	}
+	if a.cfg.Audience != "" && !contains(c.Audience, a.cfg.Audience) {
+		return nil, invalid("token not issued for audience %q", a.cfg.Audience)
+	}
+	return &c, nil
+}
+
+// verifySignature only accepts the algorithm matching the key type, so an
+// RSA key can never be used with another algorithm
+func verifySignature(alg string, key crypto.PublicKey, digest, sig []byte) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256" && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest, r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// mapScopes reads the scope (or scp) claim and translates it with ScopeMap
+func (a *JWTAuthenticator) mapScopes(c *claims) []string {
+	var raw stringList
+	for _, claim := range []json.RawMessage{c.Scope, c.SCP} {
+		if len(claim) > 0 && json.Unmarshal(claim, &raw) == nil && len(raw) > 0 {
+			break
+		}
+	}
+	if a.cfg.ScopeMap == nil {
+		return raw
+	}
+	var scopes []string
+	for _, value := range raw {
+		scopes = append(scopes, a.cfg.ScopeMap[value]...)
+	}
+	return scopes
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// signer issues test tokens and publishes its keys as a JWKS
+type signer struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+func newSigner(t *testing.T) *signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{rsaKey: rsaKey, ecKey: ecKey}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (s *signer) jwks(rsaKid, ecKid string) []byte {
+	pad := func(n *big.Int) string { return b64(n.FillBytes(make([]byte, 32))) }
+	data, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": rsaKid, "use": "sig", "n": b64(s.rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(s.rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": ecKid, "crv": "P-256", "x": pad(s.ecKey.X), "y": pad(s.ecKey.Y)},
+	}})
+	return data
+}
+
+func (s *signer) token(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, ss, err := ecdsa.Sign(rand.Reader, s.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), ss.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + b64(sig)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	s := newSigner(t)
+	now := time.Now()
+	valid := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss":   "https://idp.example.com",
+			"aud":   []string{"currency-exchange", "other"},
+			"sub":   "user-1",
+			"azp":   "billing",
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "currency.read profile",
+		}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(s.jwks("rsa-1", "ec-1"))
+	}))
+	defer jwks.Close()
+
+	a := NewJWTAuthenticator(JWTConfig{
+		Issuer:   "https://idp.example.com",
+		Audience: "currency-exchange",
+		ScopeMap: map[string][]string{"currency.read": {ScopeRead}, "currency.admin": {ScopeRead, ScopeAdmin}},
+	}, NewRemoteKeySet(jwks.URL, nil, time.Hour))
+
+	tests := []struct {
+		name           string
+		token          string
+		expectInvalid  bool
+		expectedClient string
+		expectedScopes []string
+	}{
+		{"RS256 token", s.token(t, "RS256", "rsa-1", valid(nil)), false, "billing", []string{ScopeRead}},
+		{"ES256 token", s.token(t, "ES256", "ec-1", valid(map[string]interface{}{"scp": []string{"currency.admin"}, "scope": nil})), false, "billing", []string{ScopeRead, ScopeAdmin}},
+		{"Expired", s.token(t, "RS256", "rsa-1", valid(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})), true, "", nil},
+		{"Missing expiry", s.token(t, "RS256", "rsa-1", valid(map[string]interface{}{"exp": nil})), true, "", nil},
+		{"Not yet valid", s.token(t, "RS256", "rsa-1", valid(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), true, "", nil},
+		{"Wrong issuer", s.token(t, "RS256", "rsa-1", valid(map[string]interface{}{"iss": "https://evil.example.com"})), true, "", nil},
+		{"Wrong audience", s.token(t, "RS256", "rsa-1", valid(map[string]interface{}{"aud": "other"})), true, "", nil},
+		{"Algorithm mismatch", s.token(t, "ES256", "rsa-1", valid(nil)), true, "", nil},
+		{"Unsigned token", s.token(t, "none", "rsa-1", valid(nil)), true, "", nil},
+		{"Unknown key", s.token(t, "RS256", "rsa-9", valid(nil)), true, "", nil},
+		{"Tampered claims", s.token(t, "RS256", "rsa-1", valid(nil))[:20] + "x" + s.token(t, "RS256", "rsa-1", valid(nil))[21:], true, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/rates", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			id, err := a.Authenticate(req)
+
+			if tt.expectInvalid {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Expected invalid credentials, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if id.ClientID != tt.expectedClient || !reflect.DeepEqual(id.Scopes, tt.expectedScopes) {
+				t.Errorf("Expected %s with %v, got %s with %v", tt.expectedClient, tt.expectedScopes, id.ClientID, id.Scopes)
+			}
+		})
+	}
+
+	if _, err := a.Authenticate(httptest.NewRequest("GET", "/rates", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials without a bearer token, got %v", err)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	old, rotated := newSigner(t), newSigner(t)
+
+	var mu sync.Mutex
+	current, fetches := old.jwks("k1", "e1"), 0
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		w.Write(current)
+	}))
+	defer jwks.Close()
+
+	clock := time.Now()
+	keys := NewRemoteKeySet(jwks.URL, nil, time.Hour)
+	keys.now = func() time.Time { return clock }
+	a := NewJWTAuthenticator(JWTConfig{Audience: "currency-exchange"}, keys)
+
+	authenticate := func(s *signer, kid string) error {
+		req := httptest.NewRequest("GET", "/rates", nil)
+		req.Header.Set("Authorization", "Bearer "+s.token(t, "RS256", kid, map[string]interface{}{
+			"aud": "currency-exchange", "sub": "svc", "exp": clock.Add(time.Hour).Unix(),
+		}))
+		_, err := a.Authenticate(req)
+		return err
+	}
+
+	if err := authenticate(old, "k1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := authenticate(old, "k1"); err != nil || fetches != 1 {
+		t.Fatalf("Expected cached keys to be reused, got %d fetches and %v", fetches, err)
+	}
+
+	mu.Lock()
+	current = rotated.jwks("k2", "e2")
+	mu.Unlock()
+
+	if err := authenticate(rotated, "k2"); err == nil {
+		t.Fatalf("Expected unknown key to be rejected within the forced refresh interval")
+	}
+	clock = clock.Add(2 * minForcedRefresh)
+	if err := authenticate(rotated, "k2"); err != nil {
+		t.Fatalf("Expected rotated key to be fetched, got %v", err)
+	}
+	if err := authenticate(old, "k1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected retired key to be rejected, got %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("Expected 2 JWKS fetches, got %d", fetches)
+	}
+}
+
+func TestKeySetReloadOutsideLock(t *testing.T) {
+	s := newSigner(t)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	keys := newKeySet(func(ctx context.Context) ([]byte, error) {
+		if fetches.Add(1) == 1 {
+			return s.jwks("k1", "e1"), nil
+		}
+		// Later fetches hang until released, then fail
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil, errors.New("jwks unavailable")
+	}, time.Hour)
+	clock := time.Now()
+	keys.now = func() time.Time { return clock }
+
+	if _, err := keys.Key(context.Background(), "k1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock = clock.Add(2 * minForcedRefresh)
+
+	// An unknown key ID starts a reload that hangs; its caller gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	forged := make(chan error, 1)
+	go func() {
+		_, err := keys.Key(ctx, "forged")
+		forged <- err
+	}()
+	for fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-forged; !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected the cancelled caller to stop waiting with ErrInvalidCredentials, got %v", err)
+	}
+
+	// Known keys are served while the reload is in progress
+	done := make(chan error, 1)
+	go func() {
+		_, err := keys.Key(context.Background(), "k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a cached key not to wait for the reload")
+	}
+
+	// Another unknown key ID joins the same reload, which was not cancelled
+	// with the first caller, and fails as invalid credentials
+	result := make(chan error, 1)
+	go func() {
+		_, err := keys.Key(context.Background(), "other")
+		result <- err
+	}()
+	close(release)
+	if err := <-result; !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials after a failed reload, got %v", err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("Expected the reload to be shared, got %d fetches", n)
+	}
+}
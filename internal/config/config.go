@@ -0,0 +1,511 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"currency_go_microservice/internal/server"
+)
+
+// Config is the effective service configuration. Each setting can come from
+// the defaults, a YAML or JSON config file, an environment variable (env
+// tag) or a command-line flag (flag tag), in increasing order of precedence.
+type Config struct {
+	Server    ServerConfig    `yaml:"server" json:"server"`
+	GRPC      GRPCConfig      `yaml:"grpc" json:"grpc"`
+	API       APIConfig       `yaml:"api" json:"api"`
+	Rates     RatesConfig     `yaml:"rates" json:"rates"`
+	Logging   LoggingConfig   `yaml:"logging" json:"logging"`
+	Tracing   TracingConfig   `yaml:"tracing" json:"tracing"`
+	Auth      AuthConfig      `yaml:"auth" json:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" json:"rate_limit"`
+	CORS      CORSConfig      `yaml:"cors" json:"cors"`
+	Stream    StreamConfig    `yaml:"stream" json:"stream"`
+	Features  FeaturesConfig  `yaml:"features" json:"features"`
+}
+
+// ServerConfig holds the HTTP server settings
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" json:"addr" env:"LISTEN_ADDR" flag:"addr" usage:"address the HTTP server listens on"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" json:"read_timeout" env:"HTTP_READ_TIMEOUT" flag:"read-timeout" usage:"maximum time to read a request"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" json:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"maximum time to read request headers"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" json:"write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"write-timeout" usage:"maximum time to write a response"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" json:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"idle-timeout" usage:"keep-alive idle timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" json:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" flag:"max-header-bytes" usage:"maximum request header size in bytes"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long in-flight requests may drain on shutdown"`
+	TLS               TLSConfig     `yaml:"tls" json:"tls"`
+}
+
+// TLSConfig holds the native TLS settings. TLS is enabled once a
+// certificate and key are set.
+type TLSConfig struct {
+	CertFile       string        `yaml:"cert_file" json:"cert_file" env:"TLS_CERT_FILE" flag:"tls-cert" usage:"PEM certificate served over TLS"`
+	KeyFile        string        `yaml:"key_file" json:"key_file" env:"TLS_KEY_FILE" flag:"tls-key" usage:"PEM private key for the TLS certificate"`
+	ClientCAFile   string        `yaml:"client_ca_file" json:"client_ca_file" env:"TLS_CLIENT_CA_FILE" flag:"tls-client-ca" usage:"PEM CA bundle that verifies client certificates, enables mutual TLS"`
+	ClientAuth     string        `yaml:"client_auth" json:"client_auth" env:"TLS_CLIENT_AUTH" flag:"tls-client-auth" usage:"client certificate policy with a client CA: optional or require"`
+	ReloadInterval time.Duration `yaml:"reload_interval" json:"reload_interval" env:"TLS_RELOAD_INTERVAL" flag:"tls-reload-interval" usage:"how often certificate files are checked for changes, 0 disables"`
+}
+
+// Enabled reports whether the server terminates TLS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// GRPCConfig holds the gRPC server settings. TLS and authentication are
+// shared with the HTTP server.
+type GRPCConfig struct {
+	Addr string `yaml:"addr" json:"addr" env:"GRPC_ADDR" flag:"grpc-addr" usage:"address the gRPC server listens on, empty disables gRPC"`
+}
+
+// APIConfig controls the versioned HTTP API. The /v1 and /v2 paths are
+// always served; the unversioned paths are deprecated aliases of /v1.
+type APIConfig struct {
+	LegacyRoutes bool      `yaml:"legacy_routes" json:"legacy_routes" env:"API_LEGACY_ROUTES" flag:"legacy-routes" usage:"serve the unversioned paths as deprecated aliases of /v1"`
+	DeprecatedAt time.Time `yaml:"deprecated_at" json:"deprecated_at" env:"API_LEGACY_DEPRECATED_AT" flag:"legacy-deprecated-at" usage:"RFC 3339 time the unversioned paths were deprecated, sent in the Deprecation header"`
+	Sunset       time.Time `yaml:"sunset" json:"sunset" env:"API_LEGACY_SUNSET" flag:"legacy-sunset" usage:"RFC 3339 time the unversioned paths will be removed, sent in the Sunset header"`
+}
+
+// RatesConfig holds the rate source and snapshot settings
+type RatesConfig struct {
+	Providers        ProviderList  `yaml:"providers" json:"providers" env:"RATE_PROVIDER_URLS" flag:"providers" usage:"comma separated name=url rate providers"`
+	Mode             string        `yaml:"mode" json:"mode" env:"RATE_PROVIDER_MODE" flag:"provider-mode" usage:"how providers are combined: consensus or fallback"`
+	Consensus        string        `yaml:"consensus" json:"consensus" env:"RATE_CONSENSUS" flag:"consensus" usage:"consensus method: median or trimmed_mean"`
+	RefreshInterval  time.Duration `yaml:"refresh_interval" json:"refresh_interval" env:"RATES_REFRESH_INTERVAL" flag:"refresh-interval" usage:"how often rates are refreshed from providers"`
+	ProviderTimeout  time.Duration `yaml:"provider_timeout" json:"provider_timeout" env:"RATE_PROVIDER_TIMEOUT" flag:"provider-timeout" usage:"timeout for each provider fetch"`
+	MaxAge           time.Duration `yaml:"max_age" json:"max_age" env:"RATES_MAX_AGE" flag:"max-age" usage:"maximum snapshot age before rates are stale, 0 disables"`
+	StalePolicy      string        `yaml:"stale_policy" json:"stale_policy" env:"RATES_STALE_POLICY" flag:"stale-policy" usage:"what to do with stale rates: warn or reject"`
+	MaxChangePercent float64       `yaml:"max_change_percent" json:"max_change_percent" env:"RATES_MAX_CHANGE_PERCENT" flag:"max-change-percent" usage:"largest allowed rate move between snapshots"`
+	SnapshotHistory  int           `yaml:"snapshot_history" json:"snapshot_history" env:"RATES_SNAPSHOT_HISTORY" flag:"snapshot-history" usage:"how many snapshots are kept for rollback"`
+}
+
+// LoggingConfig holds the structured logging settings
+type LoggingConfig struct {
+	Level  string `yaml:"level" json:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum log level: debug, info, warn or error"`
+	Format string `yaml:"format" json:"format" env:"LOG_FORMAT" flag:"log-format" usage:"log output format: json or text"`
+}
+
+// TracingConfig holds the OpenTelemetry trace export settings
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" json:"exporter" env:"TRACING_EXPORTER" flag:"trace-exporter" usage:"where spans are sent: none, stdout or otlp"`
+	Endpoint    string  `yaml:"endpoint" json:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"trace-endpoint" usage:"OTLP/HTTP collector URL, defaults to http://localhost:4318"`
+	ServiceName string  `yaml:"service_name" json:"service_name" env:"OTEL_SERVICE_NAME" flag:"trace-service-name" usage:"service.name reported on spans"`
+	SampleRatio float64 `yaml:"sample_ratio" json:"sample_ratio" env:"TRACING_SAMPLE_RATIO" flag:"trace-sample-ratio" usage:"fraction of new traces sampled, from 0 to 1"`
+}
+
+// AuthConfig holds the client authentication settings. Authentication is
+// enabled once a credential source is configured.
+type AuthConfig struct {
+	APIKeysFile string       `yaml:"api_keys_file" json:"api_keys_file" env:"AUTH_API_KEYS_FILE" flag:"api-keys-file" usage:"YAML or JSON file of hashed API keys"`
+	JWT         JWTConfig    `yaml:"jwt" json:"jwt"`
+	ClientCerts ScopeMapping `yaml:"client_certs" json:"client_certs" env:"AUTH_CLIENT_CERTS" flag:"client-certs" usage:"comma separated subject=scope pairs granting scopes to client certificates"`
+}
+
+// JWTConfig holds the bearer token settings. Tokens are accepted once a
+// JWKS URL or file is set.
+type JWTConfig struct {
+	Issuer      string        `yaml:"issuer" json:"issuer" env:"AUTH_JWT_ISSUER" flag:"jwt-issuer" usage:"required iss claim"`
+	Audience    string        `yaml:"audience" json:"audience" env:"AUTH_JWT_AUDIENCE" flag:"jwt-audience" usage:"audience that must appear in the aud claim"`
+	JWKSURL     string        `yaml:"jwks_url" json:"jwks_url" env:"AUTH_JWKS_URL" flag:"jwks-url" usage:"URL of the identity provider's JWKS"`
+	JWKSFile    string        `yaml:"jwks_file" json:"jwks_file" env:"AUTH_JWKS_FILE" flag:"jwks-file" usage:"local JWKS file, instead of jwks_url"`
+	JWKSRefresh time.Duration `yaml:"jwks_refresh" json:"jwks_refresh" env:"AUTH_JWKS_REFRESH" flag:"jwks-refresh" usage:"how long signing keys are cached"`
+	Leeway      time.Duration `yaml:"leeway" json:"leeway" env:"AUTH_JWT_LEEWAY" flag:"jwt-leeway" usage:"clock skew tolerated on exp and nbf"`
+	Scopes      ScopeMapping  `yaml:"scopes" json:"scopes" env:"AUTH_JWT_SCOPES" flag:"jwt-scopes" usage:"comma separated claim=scope pairs mapping token scopes to service scopes"`
+}
+
+// Enabled reports whether bearer tokens are accepted
+func (j JWTConfig) Enabled() bool {
+	return j.JWKSURL != "" || j.JWKSFile != ""
+}
+
+// ScopeMapping maps token scope values or certificate subjects to the
+// service scopes they grant
+type ScopeMapping map[string][]string
+
+// UnmarshalText parses comma separated claim=scope pairs. A claim may be
+// listed several times to grant several scopes. Certificate subjects given
+// this way are common names; full subjects need the config file.
+func (m *ScopeMapping) UnmarshalText(text []byte) error {
+	mapping := make(ScopeMapping)
+	for _, entry := range strings.Split(string(text), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		claim, scope, ok := strings.Cut(entry, "=")
+		if !ok || claim == "" || scope == "" {
+			return fmt.Errorf("scope mapping %q is not claim=scope", entry)
+		}
+		mapping[claim] = append(mapping[claim], scope)
+	}
+	*m = mapping
+	return nil
+}
+
+// Enabled reports whether any credential source is configured
+func (a AuthConfig) Enabled() bool {
+	return a.APIKeysFile != "" || a.JWT.Enabled() || len(a.ClientCerts) > 0
+}
+
+// RateLimitConfig holds the per-client rate limits and quotas
+type RateLimitConfig struct {
+	Enabled       bool        `yaml:"enabled" json:"enabled" env:"RATE_LIMIT_ENABLED" flag:"rate-limit" usage:"enforce per-client rate limits and daily quotas"`
+	Plans         PlanMap     `yaml:"plans" json:"plans" env:"RATE_LIMIT_PLANS" flag:"rate-limit-plans" usage:"comma separated name=requests_per_second:burst:daily_quota plans"`
+	Clients       ClientPlans `yaml:"clients" json:"clients" env:"RATE_LIMIT_CLIENTS" flag:"rate-limit-clients" usage:"comma separated client=plan assignments"`
+	DefaultPlan   string      `yaml:"default_plan" json:"default_plan" env:"RATE_LIMIT_DEFAULT_PLAN" flag:"rate-limit-default-plan" usage:"plan for authenticated clients without an assignment"`
+	AnonymousPlan string      `yaml:"anonymous_plan" json:"anonymous_plan" env:"RATE_LIMIT_ANONYMOUS_PLAN" flag:"rate-limit-anonymous-plan" usage:"plan for unauthenticated callers, keyed by IP"`
+}
+
+// Plan is a named set of limits
+type Plan struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `yaml:"burst" json:"burst"`
+	DailyQuota        int     `yaml:"daily_quota" json:"daily_quota"`
+}
+
+// PlanMap holds plans by name
+type PlanMap map[string]Plan
+
+// UnmarshalText parses comma separated name=rate:burst:quota entries
+func (m *PlanMap) UnmarshalText(text []byte) error {
+	plans := make(PlanMap)
+	for _, entry := range strings.Split(string(text), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, limits, _ := strings.Cut(entry, "=")
+		var p Plan
+		if _, err := fmt.Sscanf(limits, "%g:%d:%d", &p.RequestsPerSecond, &p.Burst, &p.DailyQuota); err != nil || name == "" {
+			return fmt.Errorf("plan %q is not name=requests_per_second:burst:daily_quota", entry)
+		}
+		plans[name] = p
+	}
+	*m = plans
+	return nil
+}
+
+// ClientPlans assigns plans to client IDs
+type ClientPlans map[string]string
+
+// UnmarshalText parses comma separated client=plan pairs
+func (m *ClientPlans) UnmarshalText(text []byte) error {
+	clients := make(ClientPlans)
+	for _, entry := range strings.Split(string(text), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		client, plan, ok := strings.Cut(entry, "=")
+		if !ok || client == "" || plan == "" {
+			return fmt.Errorf("client plan %q is not client=plan", entry)
+		}
+		clients[client] = plan
+	}
+	*m = clients
+	return nil
+}
+
+// CORSConfig holds the cross-origin settings for browser clients. CORS is
+// enabled once an allowed origin is set.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" json:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" flag:"cors-origins" usage:"comma separated origins allowed to call the API, * and https://*.example.com wildcards allowed"`
+	AllowedMethods   []string      `yaml:"allowed_methods" json:"allowed_methods" env:"CORS_ALLOWED_METHODS" flag:"cors-methods" usage:"comma separated methods browsers may use"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" json:"allowed_headers" env:"CORS_ALLOWED_HEADERS" flag:"cors-headers" usage:"comma separated request headers browsers may send"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" json:"exposed_headers" env:"CORS_EXPOSED_HEADERS" flag:"cors-exposed-headers" usage:"comma separated response headers scripts may read"`
+	AllowCredentials bool          `yaml:"allow_credentials" json:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" flag:"cors-credentials" usage:"allow credentialed cross-origin requests"`
+	MaxAge           time.Duration `yaml:"max_age" json:"max_age" env:"CORS_MAX_AGE" flag:"cors-max-age" usage:"how long browsers may cache preflight results"`
+}
+
+// Enabled reports whether cross-origin requests are answered
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// StreamConfig holds the settings of the /rates/stream and /rates/ws push
+// endpoints. WebSocket origins are checked against cors.allowed_origins.
+type StreamConfig struct {
+	Heartbeat    time.Duration `yaml:"heartbeat" json:"heartbeat" env:"STREAM_HEARTBEAT" flag:"stream-heartbeat" usage:"how often idle rate streams get a heartbeat"`
+	WriteTimeout time.Duration `yaml:"write_timeout" json:"write_timeout" env:"STREAM_WRITE_TIMEOUT" flag:"stream-write-timeout" usage:"how long a stream client may take to accept a message before it is dropped"`
+	MaxClients   int           `yaml:"max_clients" json:"max_clients" env:"STREAM_MAX_CLIENTS" flag:"stream-max-clients" usage:"maximum concurrent rate stream clients, 0 for no limit"`
+}
+
+// FeaturesConfig toggles optional behaviour
+type FeaturesConfig struct {
+	AnomalyGuard  bool `yaml:"anomaly_guard" json:"anomaly_guard" env:"FEATURE_ANOMALY_GUARD" flag:"anomaly-guard" usage:"validate new snapshots before installing them"`
+	SnapshotAdmin bool `yaml:"snapshot_admin" json:"snapshot_admin" env:"FEATURE_SNAPSHOT_ADMIN" flag:"snapshot-admin" usage:"serve the /snapshots admin endpoints, which requires authentication"`
+}
+
+// Provider is a named upstream rate source
+type Provider struct {
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+}
+
+// ProviderList is a list of providers, written as name=url pairs separated
+// by commas in environment variables and flags
+type ProviderList []Provider
+
+// UnmarshalText parses a comma separated list of name=url pairs. Entries
+// without a name are called provider-1, provider-2 and so on.
+func (l *ProviderList) UnmarshalText(text []byte) error {
+	var providers ProviderList
+	for i, entry := range strings.Split(string(text), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rawURL, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, rawURL = fmt.Sprintf("provider-%d", i+1), name
+		}
+		providers = append(providers, Provider{Name: name, URL: rawURL})
+	}
+	*l = providers
+	return nil
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() Config {
+	srv := server.DefaultConfig()
+	return Config{
+		Server: ServerConfig{
+			Addr:              srv.Addr,
+			ReadTimeout:       srv.ReadTimeout,
+			ReadHeaderTimeout: srv.ReadHeaderTimeout,
+			WriteTimeout:      srv.WriteTimeout,
+			IdleTimeout:       srv.IdleTimeout,
+			MaxHeaderBytes:    srv.MaxHeaderBytes,
+			ShutdownTimeout:   srv.ShutdownTimeout,
+			TLS: TLSConfig{
+				ClientAuth:     "optional",
+				ReloadInterval: time.Minute,
+			},
+		},
+		API: APIConfig{
+			LegacyRoutes: true,
+		},
+		Rates: RatesConfig{
+			Mode:             "consensus",
+			Consensus:        "median",
+			RefreshInterval:  time.Minute,
+			ProviderTimeout:  5 * time.Second,
+			StalePolicy:      "warn",
+			MaxChangePercent: 20,
+			SnapshotHistory:  50,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "currency-exchange",
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				JWKSRefresh: time.Hour,
+				Leeway:      time.Minute,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Plans: PlanMap{
+				"anonymous": {RequestsPerSecond: 5, Burst: 10, DailyQuota: 1000},
+				"standard":  {RequestsPerSecond: 50, Burst: 100, DailyQuota: 100000},
+			},
+			DefaultPlan:   "standard",
+			AnonymousPlan: "anonymous",
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "HEAD", "POST"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-API-Key", "X-Request-ID"},
+			ExposedHeaders: []string{
+				"X-Request-ID", "Retry-After", "Deprecation", "Sunset", "Link",
+				"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+				"X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
+			},
+			MaxAge: 10 * time.Minute,
+		},
+		Stream: StreamConfig{
+			Heartbeat:    15 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			MaxClients:   1000,
+		},
+		Features: FeaturesConfig{
+			AnomalyGuard: true,
+		},
+	}
+}
+
+// ServerSettings converts the server section to server.Config
+func (c Config) ServerSettings() server.Config {
+	return server.Config{
+		Addr:              c.Server.Addr,
+		ReadTimeout:       c.Server.ReadTimeout,
+		ReadHeaderTimeout: c.Server.ReadHeaderTimeout,
+		WriteTimeout:      c.Server.WriteTimeout,
+		IdleTimeout:       c.Server.IdleTimeout,
+		MaxHeaderBytes:    c.Server.MaxHeaderBytes,
+		ShutdownTimeout:   c.Server.ShutdownTimeout,
+		TLS: server.TLSConfig{
+			CertFile:       c.Server.TLS.CertFile,
+			KeyFile:        c.Server.TLS.KeyFile,
+			ClientCAFile:   c.Server.TLS.ClientCAFile,
+			ClientAuth:     c.Server.TLS.ClientAuth,
+			ReloadInterval: c.Server.TLS.ReloadInterval,
+		},
+	}
+}
+
+// Validate reports every invalid setting
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr must not be empty")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.API.Sunset.IsZero() || c.API.Sunset.After(c.API.DeprecatedAt), "api.sunset must be after api.deprecated_at")
+	check(c.GRPC.Addr == "" || c.GRPC.Addr != c.Server.Addr, "grpc.addr must differ from server.addr")
+	if tls := c.Server.TLS; tls.Enabled() {
+		check(tls.CertFile != "" && tls.KeyFile != "", "server.tls.cert_file and server.tls.key_file must be set together")
+		check(tls.ClientAuth == "optional" || tls.ClientAuth == "require", "server.tls.client_auth must be optional or require, got %q", tls.ClientAuth)
+		check(tls.ReloadInterval >= 0, "server.tls.reload_interval must not be negative")
+	} else {
+		check(tls.ClientCAFile == "", "server.tls.client_ca_file requires server.tls.cert_file and key_file")
+	}
+
+	check(c.Rates.Mode == "consensus" || c.Rates.Mode == "fallback", "rates.mode must be consensus or fallback, got %q", c.Rates.Mode)
+	check(c.Rates.Consensus == "median" || c.Rates.Consensus == "trimmed_mean", "rates.consensus must be median or trimmed_mean, got %q", c.Rates.Consensus)
+	check(c.Rates.StalePolicy == "warn" || c.Rates.StalePolicy == "reject", "rates.stale_policy must be warn or reject, got %q", c.Rates.StalePolicy)
+	check(c.Rates.RefreshInterval > 0, "rates.refresh_interval must be positive")
+	check(c.Rates.ProviderTimeout > 0, "rates.provider_timeout must be positive")
+	check(c.Rates.MaxAge >= 0, "rates.max_age must not be negative")
+	check(c.Rates.MaxChangePercent >= 0, "rates.max_change_percent must not be negative")
+	check(c.Rates.SnapshotHistory > 0, "rates.snapshot_history must be positive")
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	check(c.Logging.Format == "json" || c.Logging.Format == "text", "logging.format must be json or text, got %q", c.Logging.Format)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		check(false, "tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	if c.Tracing.Endpoint != "" {
+		u, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"tracing.endpoint must be an http or https URL")
+	}
+
+	if c.Auth.JWT.Enabled() {
+		jwt := c.Auth.JWT
+		check(jwt.JWKSURL == "" || jwt.JWKSFile == "", "auth.jwt.jwks_url and auth.jwt.jwks_file are exclusive")
+		check(jwt.Issuer != "", "auth.jwt.issuer is required when tokens are accepted")
+		check(jwt.Audience != "", "auth.jwt.audience is required when tokens are accepted")
+		check(jwt.JWKSRefresh > 0, "auth.jwt.jwks_refresh must be positive")
+		check(jwt.Leeway >= 0, "auth.jwt.leeway must not be negative")
+		if jwt.JWKSURL != "" {
+			u, err := url.Parse(jwt.JWKSURL)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+				"auth.jwt.jwks_url must be an http or https URL")
+		}
+	}
+	if c.CORS.Enabled() {
+		for _, origin := range c.CORS.AllowedOrigins {
+			u, err := url.Parse(strings.Replace(origin, "*", "x", 1))
+			check(origin == "*" || (err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == ""),
+				"cors.allowed_origins entry %q must be * or a scheme://host origin", origin)
+			check(strings.Count(origin, "*") <= 1, "cors.allowed_origins entry %q may contain one wildcard", origin)
+		}
+		check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"),
+			"cors.allow_credentials cannot be combined with the * origin; list the trusted origins")
+		check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+	}
+	check(c.Stream.Heartbeat > 0, "stream.heartbeat must be positive")
+	check(c.Stream.WriteTimeout > 0, "stream.write_timeout must be positive")
+	check(c.Stream.MaxClients >= 0, "stream.max_clients must not be negative")
+	check(len(c.Auth.ClientCerts) == 0 || c.Server.TLS.ClientCAFile != "", "auth.client_certs requires server.tls.client_ca_file")
+	// Without authentication anyone could roll back the rates
+	check(!c.Features.SnapshotAdmin || c.Auth.Enabled(), "features.snapshot_admin requires authentication (auth.api_keys_file, auth.jwt or auth.client_certs)")
+
+	if c.RateLimit.Enabled {
+		rl := c.RateLimit
+		for _, name := range []string{rl.DefaultPlan, rl.AnonymousPlan} {
+			_, ok := rl.Plans[name]
+			check(ok, "rate_limit plan %q is not defined", name)
+		}
+		for client, name := range rl.Clients {
+			_, ok := rl.Plans[name]
+			check(ok, "rate_limit.clients %s uses undefined plan %q", client, name)
+		}
+		for name, p := range rl.Plans {
+			check(p.RequestsPerSecond > 0 && p.Burst >= 1 && p.DailyQuota >= 0,
+				"rate_limit.plans %s needs a positive rate and burst and a non-negative quota", name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range c.Rates.Providers {
+		check(p.Name != "", "rates.providers entries need a name")
+		check(!seen[p.Name], "rates.providers name %q is used twice", p.Name)
+		seen[p.Name] = true
+		u, err := url.Parse(p.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"rates.providers %q needs an http or https URL", p.Name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy safe to print: passwords and query parameters in
+// provider URLs are replaced
+func (c Config) Redacted() Config {
+	providers := make(ProviderList, len(c.Rates.Providers))
+	for i, p := range c.Rates.Providers {
+		providers[i] = Provider{Name: p.Name, URL: redactURL(p.URL)}
+	}
+	c.Rates.Providers = providers
+	if c.Tracing.Endpoint != "" {
+		c.Tracing.Endpoint = redactURL(c.Tracing.Endpoint)
+	}
+	if c.Auth.JWT.JWKSURL != "" {
+		c.Auth.JWT.JWKSURL = redactURL(c.Auth.JWT.JWKSURL)
+	}
+	return c
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "REDACTED"
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, "REDACTED")
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
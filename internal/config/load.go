@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a single configurable field with its env and flag names
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value reflect.Value
+}
+
+// settings walks cfg and returns every field tagged with env and flag names
+func settings(cfg *Config) []setting {
+	var out []setting
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field, value := t.Field(i), v.Field(i)
+			if env, ok := field.Tag.Lookup("env"); ok {
+				out = append(out, setting{env: env, flag: field.Tag.Get("flag"), usage: field.Tag.Get("usage"), value: value})
+				continue
+			}
+			if value.Kind() == reflect.Struct {
+				walk(value)
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return out
+}
+
+// Load builds the configuration from defaults, the config file, environment
+// variables and command-line flags, each overriding the previous layer. The
+// config file is taken from the -config flag or the CONFIG_FILE variable.
+// The result is validated.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	fields := settings(&cfg)
+
+	fs := flag.NewFlagSet("currency-service", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a YAML or JSON config file (env CONFIG_FILE)")
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		name := f.flag
+		usage := fmt.Sprintf("%s (env %s)", f.usage, f.env)
+		record := func(v string) error { flagValues[name] = v; return nil }
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(name, usage, record)
+		} else {
+			fs.Func(name, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, f := range fields {
+		if raw := getenv(f.env); raw != "" {
+			if err := set(f.value, raw); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", f.env, err)
+			}
+		}
+	}
+	for _, f := range fields {
+		if raw, ok := flagValues[f.flag]; ok {
+			if err := set(f.value, raw); err != nil {
+				return cfg, fmt.Errorf("invalid -%s: %w", f.flag, err)
+			}
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// loadFile merges a YAML or JSON file into cfg. Unknown keys are rejected
+// so typos do not go unnoticed.
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// set parses raw into the field v
+func set(v reflect.Value, raw string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported setting type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// Print writes the configuration as YAML with secrets redacted
+func Print(w io.Writer, cfg Config) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
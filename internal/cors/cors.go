@@ -0,0 +1,205 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"currency_go_microservice/internal/router"
+)
+
+// Config lists what browsers on other origins may do
+type Config struct {
+	// AllowedOrigins are exact origins such as "https://shop.example.com",
+	// subdomain wildcards such as "https://*.example.com", or "*" for any
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD and POST
+	AllowedMethods []string
+	// AllowedHeaders are request headers a preflight may ask for; "*"
+	// allows any
+	AllowedHeaders []string
+	// ExposedHeaders are response headers scripts may read
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and client certificates.
+	// It only applies to listed origins, never to ones allowed by "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight result, 0 leaves
+	// it to the browser
+	MaxAge time.Duration
+}
+
+// Middleware answers preflight requests and marks responses to allowed
+// origins. It must run before routing so OPTIONS preflights are not
+// rejected as disallowed methods. Requests from other origins pass through
+// without CORS headers, which the browser then blocks.
+func Middleware(cfg Config) router.Middleware {
+	p := newPolicy(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Origin")
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				p.preflight(h, origin, r.Header.Get("Access-Control-Request-Method"), r.Header.Get("Access-Control-Request-Headers"))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Add("Vary", "Origin")
+			if p.originAllowed(origin) {
+				p.allowOrigin(h, origin)
+				if len(p.exposed) > 0 {
+					h.Set("Access-Control-Expose-Headers", p.exposed)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OriginChecker reports whether cfg allows origin. WebSocket handshakes are
+// not covered by CORS, so endpoints that upgrade use it to apply the same
+// origin list.
+func OriginChecker(cfg Config) func(origin string) bool {
+	return newPolicy(cfg).originAllowed
+}
+
+type policy struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	wildcards   []wildcard
+	methods     map[string]bool
+	methodList  string
+	anyHeader   bool
+	headers     map[string]bool
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// wildcard is an origin pattern split around its "*"
+type wildcard struct {
+	prefix, suffix string
+}
+
+func (w wildcard) match(origin string) bool {
+	return len(origin) > len(w.prefix)+len(w.suffix) &&
+		strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix)
+}
+
+func newPolicy(cfg Config) *policy {
+	p := &policy{
+		origins:     make(map[string]bool),
+		methods:     make(map[string]bool),
+		headers:     make(map[string]bool),
+		exposed:     strings.Join(cfg.ExposedHeaders, ", "),
+		credentials: cfg.AllowCredentials,
+	}
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		switch {
+		case o == "*":
+			p.anyOrigin = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			p.wildcards = append(p.wildcards, wildcard{prefix, suffix})
+		case o != "":
+			p.origins[o] = true
+		}
+	}
+
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	if len(cfg.AllowedMethods) > 0 {
+		methods = make([]string, len(cfg.AllowedMethods))
+		for i, m := range cfg.AllowedMethods {
+			methods[i] = strings.ToUpper(m)
+		}
+	}
+	for _, m := range methods {
+		p.methods[m] = true
+	}
+	p.methodList = strings.Join(methods, ", ")
+
+	for _, h := range cfg.AllowedHeaders {
+		if h == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return p
+}
+
+func (p *policy) originAllowed(origin string) bool {
+	return p.anyOrigin || p.listed(origin)
+}
+
+// listed reports whether origin is allowed by name or subdomain wildcard
+// rather than by "*"
+func (p *policy) listed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if w.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin names the origin the response is shared with. Credentialed
+// responses may not use "*", so listed origins are echoed instead; origins
+// only allowed by "*" get "*" without credentials, so "*" never lets an
+// arbitrary site make credentialed requests.
+func (p *policy) allowOrigin(h http.Header, origin string) {
+	switch {
+	case p.credentials && p.listed(origin):
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+	case p.anyOrigin:
+		h.Set("Access-Control-Allow-Origin", "*")
+	default:
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+}
+
+// preflight sets the response headers for an allowed preflight. A refused
+// preflight gets none, so the browser does not send the actual request.
+func (p *policy) preflight(h http.Header, origin, method, requested string) {
+	if !p.originAllowed(origin) || !p.methods[strings.ToUpper(method)] {
+		return
+	}
+	var headers []string
+	for _, name := range strings.Split(requested, ",") {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !p.anyHeader && !p.headers[name] {
+			return
+		}
+		headers = append(headers, name)
+	}
+
+	p.allowOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", p.methodList)
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+}
@@ -0,0 +1,177 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/router"
+)
+
+func TestMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "called")
+	})
+	handler := Middleware(Config{
+		AllowedOrigins:   []string{"https://shop.example.com", "https://*.checkout.example.com"},
+		AllowedMethods:   []string{"get", "post"},
+		AllowedHeaders:   []string{"Content-Type", "X-API-Key"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})(next)
+	open := Middleware(Config{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})(next)
+	// Credentials only extend to the listed origin, never to "*"
+	mixed := Middleware(Config{AllowedOrigins: []string{"https://shop.example.com", "*"}, AllowCredentials: true})(next)
+
+	tests := []struct {
+		name            string
+		handler         http.Handler
+		method          string
+		origin          string
+		requestMethod   string
+		requestHeaders  string
+		expectedStatus  int
+		expectedOrigin  string
+		expectedHeaders string
+		expectedHandler bool
+		credentials     bool
+	}{
+		{"Same origin", handler, "GET", "", "", "", http.StatusOK, "", "", true, false},
+		{"Allowed origin", handler, "GET", "https://shop.example.com", "", "", http.StatusOK, "https://shop.example.com", "", true, true},
+		{"Wildcard subdomain", handler, "GET", "https://eu.checkout.example.com", "", "", http.StatusOK, "https://eu.checkout.example.com", "", true, true},
+		{"Wildcard needs a subdomain", handler, "GET", "https://.checkout.example.com", "", "", http.StatusOK, "", "", true, false},
+		{"Unknown origin", handler, "GET", "https://evil.example.net", "", "", http.StatusOK, "", "", true, false},
+		{"Preflight", handler, "OPTIONS", "https://shop.example.com", "GET", "x-api-key, content-type", http.StatusNoContent, "https://shop.example.com", "X-Api-Key, Content-Type", false, true},
+		{"Preflight disallowed method", handler, "OPTIONS", "https://shop.example.com", "DELETE", "", http.StatusNoContent, "", "", false, false},
+		{"Preflight disallowed header", handler, "OPTIONS", "https://shop.example.com", "GET", "X-Debug", http.StatusNoContent, "", "", false, false},
+		{"Preflight unknown origin", handler, "OPTIONS", "https://evil.example.net", "GET", "", http.StatusNoContent, "", "", false, false},
+		{"Plain OPTIONS", handler, "OPTIONS", "https://shop.example.com", "", "", http.StatusOK, "https://shop.example.com", "", true, true},
+		{"Any origin", open, "GET", "https://anyone.example.org", "", "", http.StatusOK, "*", "", true, false},
+		{"Any header", open, "OPTIONS", "https://anyone.example.org", "GET", "X-Debug", http.StatusNoContent, "*", "X-Debug", false, false},
+		{"Credentials for a listed origin", mixed, "GET", "https://shop.example.com", "", "", http.StatusOK, "https://shop.example.com", "", true, true},
+		{"No credentials for any origin", mixed, "GET", "https://evil.example.net", "", "", http.StatusOK, "*", "", true, false},
+		{"No credentialed preflight for any origin", mixed, "OPTIONS", "https://evil.example.net", "GET", "", http.StatusNoContent, "*", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/exchange", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			if tt.requestHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.requestHeaders)
+			}
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("Expected allowed origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Headers"); got != tt.expectedHeaders {
+				t.Errorf("Expected allowed headers %q, got %q", tt.expectedHeaders, got)
+			}
+			if called := rr.Header().Get("X-Handler") != ""; called != tt.expectedHandler {
+				t.Errorf("Expected handler called %v, got %v", tt.expectedHandler, called)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+				t.Errorf("Expected credentials allowed %v, got %v", tt.credentials, got)
+			}
+			if tt.origin != "" && rr.Header().Get("Vary") != "Origin" {
+				t.Errorf("Expected Vary: Origin, got %q", rr.Header().Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestPreflightHeaders(t *testing.T) {
+	handler := Middleware(Config{
+		AllowedOrigins:   []string{"https://shop.example.com"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+		ExposedHeaders:   []string{"X-Request-ID", "Retry-After"},
+	})(http.NotFoundHandler())
+
+	req := httptest.NewRequest("OPTIONS", "/exchange", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	expected := map[string]string{
+		"Access-Control-Allow-Methods":     "GET, HEAD, POST",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for name, value := range expected {
+		if got := rr.Header().Get(name); got != value {
+			t.Errorf("Expected %s %q, got %q", name, value, got)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/exchange", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID, Retry-After" {
+		t.Errorf("Expected exposed headers, got %q", got)
+	}
+}
+
+func TestPreflightBeforeRouting(t *testing.T) {
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+		})
+	}
+	rt := router.New(nil)
+	rt.Use(Middleware(Config{AllowedOrigins: []string{"https://shop.example.com"}, AllowedHeaders: []string{"X-API-Key"}}))
+	rt.HandleFunc(http.MethodGet, "/exchange", func(w http.ResponseWriter, r *http.Request) {}, deny)
+
+	req := httptest.NewRequest("OPTIONS", "/exchange", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent || rr.Header().Get("Access-Control-Allow-Origin") != "https://shop.example.com" {
+		t.Errorf("Expected preflight answered before the route, got %d %v", rr.Code, rr.Header())
+	}
+
+	// The actual request still goes through the route and keeps CORS
+	// headers on errors, so the browser can read the failure
+	req = httptest.NewRequest("GET", "/exchange", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || rr.Header().Get("Access-Control-Allow-Origin") != "https://shop.example.com" {
+		t.Errorf("Expected 401 with CORS headers, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+func TestOriginChecker(t *testing.T) {
+	allowed := OriginChecker(Config{AllowedOrigins: []string{"https://shop.example.com", "https://*.example.org"}})
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://shop.example.com", true},
+		{"HTTPS://SHOP.EXAMPLE.COM", true},
+		{"https://eu.example.org", true},
+		{"https://evil.example.net", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := allowed(tt.origin); got != tt.expected {
+			t.Errorf("OriginChecker(%q) = %v, expected %v", tt.origin, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"currency_go_microservice/internal/service"
+)
+
+// httpCodes maps the statuses of the HTTP ErrorResponse cases to the gRPC
+// codes with the same meaning
+var httpCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+}
+
+// CodeForHTTPStatus returns the gRPC code for an HTTP error status
+func CodeForHTTPStatus(httpStatus int) codes.Code {
+	if code, ok := httpCodes[httpStatus]; ok {
+		return code
+	}
+	return codes.Unknown
+}
+
+// ErrorDomain is the domain of the errdetails.ErrorInfo attached to
+// every error status. Its reason is the code the HTTP API reports for the
+// same failure, e.g. UNSUPPORTED_CURRENCY, and its "field" metadata the
+// offending parameter, if any.
+const ErrorDomain = "currency-exchange"
+
+// statusError converts a service error to a status error with the code
+// its HTTP response would have had
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	httpStatus, code, field := service.Classify(err)
+	return newStatus(httpStatus, code, field, err.Error())
+}
+
+// httpStatusError is the status error for a failure the HTTP API reports
+// with only a status, such as those of the auth and rate limit middleware
+func httpStatusError(httpStatus int, message string) error {
+	return newStatus(httpStatus, service.CodeForStatus(httpStatus), "", message)
+}
+
+// newStatus builds a status error carrying code and field in an ErrorInfo
+func newStatus(httpStatus int, code, field, message string) error {
+	st := status.New(CodeForHTTPStatus(httpStatus), message)
+	info := &errdetails.ErrorInfo{Reason: code, Domain: ErrorDomain}
+	if field != "" {
+		info.Metadata = map[string]string{"field": field}
+	}
+	if detailed, err := st.WithDetails(info); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,221 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	currencyv1 "currency_go_microservice/api/currency/v1"
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/logging"
+	"currency_go_microservice/internal/ratelimit"
+	"currency_go_microservice/internal/service"
+)
+
+// healthPrefix names the health service, which stays unauthenticated like
+// the HTTP probes
+const healthPrefix = "/grpc.health.v1.Health/"
+
+// logUnary writes one access log line per unary call
+func logUnary(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, start := withRequestID(ctx), time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// logStream writes one access log line when a stream ends
+func logStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, start := withRequestID(ss.Context()), time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// withRequestID adopts the caller's x-request-id metadata when valid or
+// generates one, so gRPC calls correlate in logs like HTTP requests
+func withRequestID(ctx context.Context) context.Context {
+	id := ""
+	if values := metadata.ValueFromIncomingContext(ctx, logging.RequestIDHeader); len(values) > 0 {
+		id = values[0]
+	}
+	return logging.WithRequestID(ctx, logging.RequestIDOrNew(id))
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		level = slog.LevelError
+	}
+	logger.LogAttrs(ctx, level, "grpc request",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// authUnary authenticates unary calls, see authenticate
+func authUnary(authenticators []auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, healthPrefix) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, authenticators)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStream authenticates streaming calls, see authenticate
+func authStream(authenticators []auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthPrefix) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), authenticators)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate runs the HTTP authenticators against the call metadata and
+// TLS peer, so API keys, bearer tokens and client certificates work the
+// same over gRPC. Every RPC reads rates and requires auth.ScopeRead.
+func authenticate(ctx context.Context, authenticators []auth.Authenticator) (context.Context, error) {
+	id, err := auth.Authenticate(callRequest(ctx), authenticators...)
+	switch {
+	case errors.Is(err, auth.ErrNoCredentials):
+		return nil, httpStatusError(http.StatusUnauthorized, "Missing credentials")
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return nil, httpStatusError(http.StatusUnauthorized, err.Error())
+	case err != nil:
+		slog.ErrorContext(ctx, "authentication failed", "error", err)
+		return nil, httpStatusError(http.StatusServiceUnavailable, "Authentication is temporarily unavailable")
+	}
+	if !id.HasScope(auth.ScopeRead) {
+		return nil, httpStatusError(http.StatusForbidden, "Client "+id.ClientID+" lacks the "+auth.ScopeRead+" scope")
+	}
+	return auth.WithIdentity(ctx, id), nil
+}
+
+// quotaMethods are the RPCs counted against the daily conversion quota,
+// like the HTTP conversion routes
+var quotaMethods = map[string]bool{
+	currencyv1.CurrencyService_Convert_FullMethodName:      true,
+	currencyv1.CurrencyService_BatchConvert_FullMethodName: true,
+}
+
+// limitUnary applies the rate limit and quota to unary calls, see limit
+func limitUnary(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, healthPrefix) {
+			return handler(ctx, req)
+		}
+		if err := limit(ctx, limiter, info.FullMethod, conversions(req)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// limitStream applies the rate limit to opening streams, see limit
+func limitStream(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthPrefix) {
+			return handler(srv, ss)
+		}
+		if err := limit(ss.Context(), limiter, info.FullMethod, 1); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// limit counts a call against the caller's request rate and, for
+// conversions, n conversions against its daily quota, sharing the buckets
+// of the HTTP routes. Rejected calls get ResourceExhausted with retry-after
+// metadata. It must run after authentication so clients are keyed by
+// identity.
+func limit(ctx context.Context, limiter *ratelimit.Limiter, method string, n int) error {
+	r := callRequest(ctx)
+	if result, ok := limiter.TakeToken(r); ok && !result.Allowed {
+		return exhausted(ctx, result, ratelimit.RateLimitedMessage)
+	}
+	if !quotaMethods[method] {
+		return nil
+	}
+	if result, ok := limiter.ChargeQuota(r, n); ok && !result.Allowed {
+		return exhausted(ctx, result, ratelimit.QuotaExceededMessage(result.Limit))
+	}
+	return nil
+}
+
+// conversions is how many conversions req counts for against the quota:
+// the size of a batch the handler accepts, otherwise 1
+func conversions(req any) int {
+	if batch, ok := req.(*currencyv1.BatchConvertRequest); ok {
+		if n := len(batch.GetConversions()); n > 0 && n <= service.MaxBatchSize {
+			return n
+		}
+	}
+	return 1
+}
+
+// exhausted returns the ResourceExhausted status for a rejected call
+func exhausted(ctx context.Context, result ratelimit.Result, message string) error {
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", ratelimit.RetryAfter(result.RetryAfter)))
+	return httpStatusError(http.StatusTooManyRequests, message)
+}
+
+// callRequest presents a call to the authenticators as an HTTP request
+// carrying the metadata as headers and the peer's address and TLS state
+func callRequest(ctx context.Context) *http.Request {
+	r := (&http.Request{Header: make(http.Header)}).WithContext(ctx)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") {
+				continue
+			}
+			for _, v := range values {
+				r.Header.Add(key, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+	return r
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,233 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	currencyv1 "currency_go_microservice/api/currency/v1"
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/ratelimit"
+	"currency_go_microservice/internal/service"
+)
+
+// Options configures the gRPC server
+type Options struct {
+	// TLSConfig, when set, serves gRPC over TLS, e.g. the HTTP server's
+	// configuration so both share certificates and client CAs
+	TLSConfig *tls.Config
+	// Authenticators, when set, authenticate every call as on the HTTP
+	// routes, requiring auth.ScopeRead. Health checks stay open.
+	Authenticators []auth.Authenticator
+	// Limiter, when set, applies the HTTP routes' per-client rate limits to
+	// every call and their daily quotas to conversions, sharing the same
+	// buckets. Health checks are not limited.
+	Limiter *ratelimit.Limiter
+	// Logger writes one line per call; nil uses slog.Default
+	Logger *slog.Logger
+}
+
+// New creates a gRPC server exposing cs as currency.v1.CurrencyService,
+// together with the standard health and reflection services
+func New(cs *service.CurrencyService, opts Options) *grpc.Server {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	unary := []grpc.UnaryServerInterceptor{logUnary(logger)}
+	stream := []grpc.StreamServerInterceptor{logStream(logger)}
+	if len(opts.Authenticators) > 0 {
+		unary = append(unary, authUnary(opts.Authenticators))
+		stream = append(stream, authStream(opts.Authenticators))
+	}
+	if opts.Limiter != nil {
+		unary = append(unary, limitUnary(opts.Limiter))
+		stream = append(stream, limitStream(opts.Limiter))
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if opts.TLSConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(opts.TLSConfig)))
+	}
+
+	srv := grpc.NewServer(serverOpts...)
+	currencyv1.RegisterCurrencyServiceServer(srv, NewServer(cs))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	return srv
+}
+
+// Server implements currencyv1.CurrencyServiceServer on a CurrencyService
+type Server struct {
+	currencyv1.UnimplementedCurrencyServiceServer
+	cs *service.CurrencyService
+}
+
+// NewServer creates the gRPC handlers for cs
+func NewServer(cs *service.CurrencyService) *Server {
+	return &Server{cs: cs}
+}
+
+// Convert implements currencyv1.CurrencyServiceServer
+func (s *Server) Convert(ctx context.Context, req *currencyv1.ConvertRequest) (*currencyv1.ConvertResponse, error) {
+	resp, err := s.cs.Exchange(ctx, req.GetFrom(), req.GetTo(), req.GetAmount())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return conversion(resp), nil
+}
+
+// BatchConvert implements currencyv1.CurrencyServiceServer
+func (s *Server) BatchConvert(ctx context.Context, req *currencyv1.BatchConvertRequest) (*currencyv1.BatchConvertResponse, error) {
+	reqs := make([]service.ExchangeRequest, len(req.GetConversions()))
+	for i, c := range req.GetConversions() {
+		reqs[i] = service.ExchangeRequest{From: c.GetFrom(), To: c.GetTo(), Amount: c.GetAmount()}
+	}
+	results, err := s.cs.ExchangeBatch(ctx, reqs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &currencyv1.BatchConvertResponse{Results: make([]*currencyv1.ConversionResult, len(results))}
+	for i, r := range results {
+		if r.Err != nil {
+			_, code, _ := service.Classify(r.Err)
+			resp.Results[i] = &currencyv1.ConversionResult{Result: &currencyv1.ConversionResult_Error{
+				Error: &currencyv1.ConversionError{Code: code, Message: r.Err.Error()},
+			}}
+			continue
+		}
+		resp.Results[i] = &currencyv1.ConversionResult{Result: &currencyv1.ConversionResult_Conversion{Conversion: conversion(r.Response)}}
+	}
+	return resp, nil
+}
+
+// GetRates implements currencyv1.CurrencyServiceServer
+func (s *Server) GetRates(ctx context.Context, req *currencyv1.GetRatesRequest) (*currencyv1.Rates, error) {
+	return s.rates(s.cs.ActiveSnapshot(), req.GetSymbols())
+}
+
+// ListCurrencies implements currencyv1.CurrencyServiceServer
+func (s *Server) ListCurrencies(ctx context.Context, req *currencyv1.ListCurrenciesRequest) (*currencyv1.ListCurrenciesResponse, error) {
+	return &currencyv1.ListCurrenciesResponse{
+		Base:       s.cs.ActiveSnapshot().Base,
+		Currencies: s.cs.Currencies(),
+	}, nil
+}
+
+// WatchRates implements currencyv1.CurrencyServiceServer. It subscribes
+// before sending the current table so no installed snapshot is missed.
+func (s *Server) WatchRates(req *currencyv1.WatchRatesRequest, stream grpc.ServerStreamingServer[currencyv1.Rates]) error {
+	updates, cancel := s.cs.Subscribe()
+	defer cancel()
+
+	snapshot := s.cs.ActiveSnapshot()
+	for {
+		msg, err := s.rates(snapshot, req.GetSymbols())
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case next := <-updates:
+			// A snapshot installed between Subscribe and the first send
+			// arrives again; the client already has it
+			for next == snapshot {
+				select {
+				case <-stream.Context().Done():
+					return status.FromContextError(stream.Context().Err()).Err()
+				case next = <-updates:
+				}
+			}
+			snapshot = next
+		}
+	}
+}
+
+// rates converts a snapshot to the wire form, keeping only symbols when
+// given. Unknown symbols are rejected like unsupported currencies.
+func (s *Server) rates(snapshot *service.RateSnapshot, symbols []string) (*currencyv1.Rates, error) {
+	rates := snapshot.Rates
+	if len(symbols) > 0 {
+		rates = make(map[string]float64, len(symbols))
+		for _, sym := range symbols {
+			rate, ok := snapshot.Rates[strings.ToUpper(sym)]
+			if !ok {
+				return nil, statusError(&service.UnsupportedCurrencyError{Currency: sym})
+			}
+			rates[strings.ToUpper(sym)] = rate
+		}
+	}
+
+	return &currencyv1.Rates{
+		Base:    snapshot.Base,
+		Rates:   rates,
+		Version: snapshot.Version,
+		AsOf:    timestamppb.New(snapshot.AsOf),
+		Source:  snapshot.Source,
+		Warning: s.cs.StaleWarning(snapshot),
+	}, nil
+}
+
+func conversion(r *service.ExchangeResponse) *currencyv1.ConvertResponse {
+	return &currencyv1.ConvertResponse{
+		From:            r.From,
+		To:              r.To,
+		Amount:          r.Amount,
+		ConvertedAmount: r.ConvertedAmount,
+		Rate:            r.Rate,
+		Version:         r.Version,
+		AsOf:            timestamppb.New(r.AsOf),
+		Source:          r.Source,
+		Warning:         r.Warning,
+	}
+}
+
+// Serve serves srv on ln until ctx is cancelled, then stops gracefully.
+// Calls still running after shutdownTimeout, such as WatchRates streams,
+// are cancelled.
+func Serve(ctx context.Context, srv *grpc.Server, ln net.Listener, shutdownTimeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		srv.Stop()
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
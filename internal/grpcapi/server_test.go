@@ -0,0 +1,367 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	currencyv1 "currency_go_microservice/api/currency/v1"
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/ratelimit"
+	"currency_go_microservice/internal/service"
+)
+
+// dial serves cs over an in-memory listener and returns a connected client
+func dial(t *testing.T, cs *service.CurrencyService, opts Options) *grpc.ClientConn {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	srv := New(cs, opts)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// reason returns the ErrorInfo reason and field of a status error
+func reason(err error) (code, field string) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == ErrorDomain {
+			return info.GetReason(), info.GetMetadata()["field"]
+		}
+	}
+	return "", ""
+}
+
+func TestConvert(t *testing.T) {
+	client := currencyv1.NewCurrencyServiceClient(dial(t, service.NewCurrencyService(), Options{}))
+
+	tests := []struct {
+		name           string
+		req            *currencyv1.ConvertRequest
+		expectedCode   codes.Code
+		expectedReason string
+		expectedField  string
+		expected       float64
+	}{
+		{"Valid conversion", &currencyv1.ConvertRequest{From: "usd", To: "EUR", Amount: 100}, codes.OK, "", "", 85},
+		{"Missing currency", &currencyv1.ConvertRequest{From: "USD", Amount: 100}, codes.InvalidArgument, "MISSING_PARAMETER", "to", 0},
+		{"Invalid amount", &currencyv1.ConvertRequest{From: "USD", To: "EUR", Amount: -1}, codes.InvalidArgument, "INVALID_AMOUNT", "amount", 0},
+		{"Unsupported currency", &currencyv1.ConvertRequest{From: "USD", To: "XYZ", Amount: 100}, codes.InvalidArgument, "UNSUPPORTED_CURRENCY", "to", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Convert(context.Background(), tt.req)
+			if status.Code(err) != tt.expectedCode {
+				t.Fatalf("Expected code %v, got %v", tt.expectedCode, err)
+			}
+			if code, field := reason(err); code != tt.expectedReason || field != tt.expectedField {
+				t.Errorf("Expected reason %q on %q, got %q on %q", tt.expectedReason, tt.expectedField, code, field)
+			}
+			if tt.expectedCode != codes.OK {
+				return
+			}
+			if resp.GetConvertedAmount() != tt.expected || resp.GetFrom() != "USD" || resp.GetVersion() != 1 {
+				t.Errorf("Unexpected response %v", resp)
+			}
+		})
+	}
+}
+
+func TestConvertStaleRates(t *testing.T) {
+	cs := service.NewCurrencyService(service.WithMaxAge(time.Hour, service.StaleReject))
+	snapshot := service.NewRateSnapshot("USD", service.ExchangeRates)
+	snapshot.AsOf = time.Now().Add(-2 * time.Hour)
+	if err := cs.InstallSnapshot(snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := currencyv1.NewCurrencyServiceClient(dial(t, cs, Options{}))
+
+	_, err := client.Convert(context.Background(), &currencyv1.ConvertRequest{From: "USD", To: "EUR", Amount: 1})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable for stale rates, got %v", err)
+	}
+	_, err = client.BatchConvert(context.Background(), &currencyv1.BatchConvertRequest{
+		Conversions: []*currencyv1.ConvertRequest{{From: "USD", To: "EUR", Amount: 1}},
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable for stale batch, got %v", err)
+	}
+}
+
+func TestBatchConvert(t *testing.T) {
+	client := currencyv1.NewCurrencyServiceClient(dial(t, service.NewCurrencyService(), Options{}))
+
+	resp, err := client.BatchConvert(context.Background(), &currencyv1.BatchConvertRequest{
+		Conversions: []*currencyv1.ConvertRequest{
+			{From: "USD", To: "EUR", Amount: 100},
+			{From: "USD", To: "XYZ", Amount: 100},
+			{From: "GBP", Amount: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	results := resp.GetResults()
+	if len(results) != 3 || results[0].GetConversion().GetConvertedAmount() != 85 {
+		t.Fatalf("Expected first conversion to succeed, got %v", results)
+	}
+	for i, code := range []string{"UNSUPPORTED_CURRENCY", "MISSING_PARAMETER"} {
+		if r := results[i+1]; r.GetError().GetCode() != code || r.GetError().GetMessage() == "" {
+			t.Errorf("Expected %s entry error, got %v", code, r)
+		}
+	}
+
+	_, err = client.BatchConvert(context.Background(), &currencyv1.BatchConvertRequest{})
+	if code, _ := reason(err); status.Code(err) != codes.InvalidArgument || code != "MISSING_PARAMETER" {
+		t.Errorf("Expected InvalidArgument MISSING_PARAMETER for empty batch, got %v", err)
+	}
+	tooMany := make([]*currencyv1.ConvertRequest, service.MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = &currencyv1.ConvertRequest{From: "USD", To: "EUR", Amount: 1}
+	}
+	_, err = client.BatchConvert(context.Background(), &currencyv1.BatchConvertRequest{Conversions: tooMany})
+	if code, _ := reason(err); status.Code(err) != codes.InvalidArgument || code != "BATCH_TOO_LARGE" {
+		t.Errorf("Expected InvalidArgument BATCH_TOO_LARGE for oversized batch, got %v", err)
+	}
+}
+
+func TestRatesAndCurrencies(t *testing.T) {
+	client := currencyv1.NewCurrencyServiceClient(dial(t, service.NewCurrencyService(), Options{}))
+
+	rates, err := client.GetRates(context.Background(), &currencyv1.GetRatesRequest{Symbols: []string{"eur", "JPY"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rates.GetRates()) != 2 || rates.GetRates()["EUR"] != 0.85 || rates.GetBase() != "USD" || rates.GetAsOf() == nil {
+		t.Errorf("Expected EUR and JPY rates, got %v", rates)
+	}
+	if _, err := client.GetRates(context.Background(), &currencyv1.GetRatesRequest{Symbols: []string{"XYZ"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for unknown symbol, got %v", err)
+	}
+
+	list, err := client.ListCurrencies(context.Background(), &currencyv1.ListCurrenciesRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(list.GetCurrencies()) != len(service.ExchangeRates) || list.GetCurrencies()[0] != "AUD" {
+		t.Errorf("Expected sorted currency list, got %v", list.GetCurrencies())
+	}
+}
+
+func TestWatchRates(t *testing.T) {
+	cs := service.NewCurrencyService()
+	client := currencyv1.NewCurrencyServiceClient(dial(t, cs, Options{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchRates(ctx, &currencyv1.WatchRatesRequest{Symbols: []string{"EUR"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil || first.GetVersion() != 1 || len(first.GetRates()) != 1 {
+		t.Fatalf("Expected current snapshot first, got %v, %v", first, err)
+	}
+
+	rates := map[string]float64{}
+	for code, rate := range service.ExchangeRates {
+		rates[code] = rate
+	}
+	rates["EUR"] = 0.9
+	if err := cs.InstallSnapshot(service.NewRateSnapshot("USD", rates)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next, err := stream.Recv()
+	if err != nil || next.GetVersion() != 2 || next.GetRates()["EUR"] != 0.9 {
+		t.Errorf("Expected installed snapshot to be pushed, got %v, %v", next, err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	store, err := auth.NewMemoryKeyStore(
+		auth.APIKey{ClientID: "billing", Hash: auth.HashKey("reader-key"), Scopes: []string{auth.ScopeRead}},
+		auth.APIKey{ClientID: "metrics", Hash: auth.HashKey("other-key"), Scopes: []string{"metrics:read"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dial(t, service.NewCurrencyService(), Options{Authenticators: []auth.Authenticator{auth.NewAPIKeyAuthenticator(store)}})
+	client := currencyv1.NewCurrencyServiceClient(conn)
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{"Missing key", "", codes.Unauthenticated, "UNAUTHORIZED"},
+		{"Unknown key", "guess", codes.Unauthenticated, "UNAUTHORIZED"},
+		{"Missing scope", "other-key", codes.PermissionDenied, "FORBIDDEN"},
+		{"Valid key", "reader-key", codes.OK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", tt.key)
+			}
+			_, err := client.ListCurrencies(ctx, &currencyv1.ListCurrenciesRequest{})
+			if status.Code(err) != tt.expectedCode {
+				t.Errorf("Expected unary code %v, got %v", tt.expectedCode, err)
+			}
+
+			stream, err := client.WatchRates(ctx, &currencyv1.WatchRatesRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if code, _ := reason(err); status.Code(err) != tt.expectedCode || code != tt.expectedReason {
+				t.Errorf("Expected stream code %v %s, got %v", tt.expectedCode, tt.expectedReason, err)
+			}
+		})
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected open health check, got %v, %v", resp, err)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Plans: map[string]ratelimit.Plan{
+			"metered": {RequestsPerSecond: 1000, Burst: 1000, DailyQuota: 3},
+			"slow":    {RequestsPerSecond: 0.001, Burst: 1},
+		},
+		Clients:       map[string]string{"batch": "slow"},
+		DefaultPlan:   "metered",
+		AnonymousPlan: "metered",
+	}, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := auth.NewMemoryKeyStore(auth.APIKey{ClientID: "batch", Hash: auth.HashKey("batch-key"), Scopes: []string{auth.ScopeRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Anonymous calls are limited by address, authenticated ones by client
+	cs := service.NewCurrencyService()
+	conn := dial(t, cs, Options{Limiter: limiter})
+	client := currencyv1.NewCurrencyServiceClient(conn)
+	authenticated := currencyv1.NewCurrencyServiceClient(dial(t, cs, Options{
+		Authenticators: []auth.Authenticator{auth.NewAPIKeyAuthenticator(store)},
+		Limiter:        limiter,
+	}))
+	convert := &currencyv1.ConvertRequest{From: "USD", To: "EUR", Amount: 1}
+	keyed := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "batch-key")
+	batch := func(n int) error {
+		req := &currencyv1.BatchConvertRequest{}
+		for range n {
+			req.Conversions = append(req.Conversions, convert)
+		}
+		_, err := client.BatchConvert(context.Background(), req)
+		return err
+	}
+
+	tests := []struct {
+		name         string
+		call         func() error
+		expectedCode codes.Code
+	}{
+		{"Batch over the quota", func() error { return batch(4) }, codes.ResourceExhausted},
+		{"Batch of two", func() error { return batch(2) }, codes.OK},
+		{"Conversion", func() error { _, err := client.Convert(context.Background(), convert); return err }, codes.OK},
+		{"Quota used up", func() error { _, err := client.Convert(context.Background(), convert); return err }, codes.ResourceExhausted},
+		{"Batches share the quota", func() error { return batch(1) }, codes.ResourceExhausted},
+		{"Reads are not counted against the quota", func() error {
+			_, err := client.ListCurrencies(context.Background(), &currencyv1.ListCurrenciesRequest{})
+			return err
+		}, codes.OK},
+		{"First call of a slow client", func() error {
+			_, err := authenticated.GetRates(keyed, &currencyv1.GetRatesRequest{})
+			return err
+		}, codes.OK},
+		{"Rate limited call", func() error {
+			_, err := authenticated.GetRates(keyed, &currencyv1.GetRatesRequest{})
+			return err
+		}, codes.ResourceExhausted},
+		{"Rate limited stream", func() error {
+			stream, err := authenticated.WatchRates(keyed, &currencyv1.WatchRatesRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			return err
+		}, codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.expectedCode {
+				t.Errorf("Expected code %v, got %v", tt.expectedCode, err)
+			}
+		})
+	}
+
+	var header metadata.MD
+	_, err = client.Convert(context.Background(), convert, grpc.Header(&header))
+	if code, _ := reason(err); status.Code(err) != codes.ResourceExhausted || code != "RATE_LIMITED" || len(header.Get("retry-after")) != 1 {
+		t.Errorf("Expected ResourceExhausted RATE_LIMITED with retry-after metadata, got %v with %v", err, header)
+	}
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected unlimited health check, got %v, %v", resp, err)
+	}
+}
+
+func TestServeStopsStreamsOnShutdown(t *testing.T) {
+	ln := bufconn.Listen(1 << 20)
+	srv := New(service.NewCurrencyService(), Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, srv, ln, 100*time.Millisecond) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	stream, err := currencyv1.NewCurrencyServiceClient(conn).WatchRates(context.Background(), &currencyv1.WatchRatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected open stream to be cut after the shutdown timeout")
+	}
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		incoming   string
+		expectSame bool
+	}{
+		{
+			name:       "Incoming ID is propagated",
+			incoming:   "abc-123",
+			expectSame: true,
+		},
+		{
+			name:       "Missing ID is generated",
+			incoming:   "",
+			expectSame: false,
+		},
+		{
+			name:       "Unsafe ID is replaced",
+			incoming:   "bad\nid",
+			expectSame: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, "info", "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var seen string
+			handler := RequestIDs(AccessLog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestID(r.Context())
+				logger.InfoContext(r.Context(), "inside handler")
+				w.WriteHeader(http.StatusTeapot)
+			})))
+
+			req := httptest.NewRequest("GET", "/exchange", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			header := rr.Header().Get(RequestIDHeader)
+			if header == "" || header != seen {
+				t.Fatalf("Expected response header to match context ID, got %q and %q", header, seen)
+			}
+			if (header == tt.incoming) != tt.expectSame {
+				t.Errorf("Expected incoming ID reuse to be %v, got %q", tt.expectSame, header)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("Expected 2 log lines, got %d: %s", len(lines), buf.String())
+			}
+			var access map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[1]), &access); err != nil {
+				t.Fatalf("Expected JSON log line, got %s", lines[1])
+			}
+			if access["request_id"] != header || access["status"] != float64(http.StatusTeapot) || access["path"] != "/exchange" {
+				t.Errorf("Unexpected access log line: %s", lines[1])
+			}
+			if !strings.Contains(lines[0], `"request_id":"`+header+`"`) {
+				t.Errorf("Expected handler log line to carry request_id, got %s", lines[0])
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "loud", "json"); err == nil {
+		t.Errorf("Expected error for invalid level")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Errorf("Expected error for invalid format")
+	}
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("Expected only warn and above to be logged, got %s", buf.String())
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, reg *Registry) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Expected Prometheus text content type, got %q", ct)
+	}
+	return rr.Body.String()
+}
+
+func TestRegistryTextFormat(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.NewCounterVec("jobs_total", "Jobs processed.", "queue")
+	counter.Inc("default")
+	counter.Add(2, `quo"te`)
+	reg.NewGaugeFunc("temperature", "Current temperature.", []string{"room"}, func(emit func(float64, ...string)) {
+		emit(21.5, "kitchen")
+	})
+	hist := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1})
+	hist.Observe(0.05)
+	hist.Observe(0.5)
+
+	out := scrape(t, reg)
+	expected := []string{
+		"# TYPE jobs_total counter",
+		`jobs_total{queue="default"} 1`,
+		`jobs_total{queue="quo\"te"} 2`,
+		"# TYPE temperature gauge",
+		`temperature{room="kitchen"} 21.5`,
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{le="0.1"} 1`,
+		`latency_seconds_bucket{le="1"} 2`,
+		`latency_seconds_bucket{le="+Inf"} 2`,
+		"latency_seconds_sum 0.55",
+		"latency_seconds_count 2",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected line %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Index(out, "jobs_total") > strings.Index(out, "latency_seconds") {
+		t.Errorf("Expected metrics sorted by name:\n%s", out)
+	}
+}
+
+func TestHTTPMetricsMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	m := NewHTTPMetrics(reg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "ok")
+	})
+	handler := m.Middleware(mux)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/missing", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	tests := []struct {
+		endpoint string
+		status   string
+		expected float64
+	}{
+		{"/items/{id}", "200", 2},
+		{"/items/{id}", "404", 1},
+		{"unmatched", "404", 1},
+	}
+	for _, tt := range tests {
+		if got := m.requests.Value(tt.endpoint, "GET", tt.status); got != tt.expected {
+			t.Errorf("Expected %v requests for %s %s, got %v", tt.expected, tt.endpoint, tt.status, got)
+		}
+		if got := m.duration.Count(tt.endpoint, "GET", tt.status); got != uint64(tt.expected) {
+			t.Errorf("Expected %v latency observations for %s %s, got %d", tt.expected, tt.endpoint, tt.status, got)
+		}
+	}
+
+	if out := scrape(t, reg); !strings.Contains(out, `http_request_duration_seconds_bucket{endpoint="/items/{id}",method="GET",status="200",le="+Inf"} 2`) {
+		t.Errorf("Expected labelled histogram bucket in output:\n%s", out)
+	}
+}
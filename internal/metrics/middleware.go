@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"currency_go_microservice/internal/httpx"
+)
+
+// HTTPMetrics counts requests and measures latency per endpoint and status
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+// NewHTTPMetrics registers the HTTP request metrics on r
+func NewHTTPMetrics(r *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounterVec("http_requests_total",
+			"Total HTTP requests by endpoint, method and status.", "endpoint", "method", "status"),
+		duration: r.NewHistogramVec("http_request_duration_seconds",
+			"HTTP request latency by endpoint, method and status.", DefaultBuckets, "endpoint", "method", "status"),
+	}
+}
+
+// Middleware records every request. The endpoint label is the route
+// pattern matched by an http.ServeMux directly below this middleware, so
+// path parameters do not create new series; unmatched requests are
+// labelled "unmatched".
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := httpx.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+
+		endpoint := r.Pattern
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+		status := strconv.Itoa(rec.Status)
+		m.requests.Inc(endpoint, r.Method, status)
+		m.duration.Observe(time.Since(start).Seconds(), endpoint, r.Method, status)
+	})
+}
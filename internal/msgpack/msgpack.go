@@ -0,0 +1,254 @@
+// Package msgpack encodes values in the MessagePack format
+// (https://msgpack.org/). It covers what the API responses need: structs
+// are written as maps keyed by their json tag names, honouring omitempty
+// and "-", so a MessagePack response mirrors its JSON form.
+package msgpack
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Marshal returns the MessagePack encoding of v. Values implementing
+// encoding.TextMarshaler, such as time.Time, are written as strings.
+func Marshal(v any) ([]byte, error) {
+	var e encoder
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// UnsupportedTypeError is returned for values with no MessagePack form
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "msgpack: unsupported type " + e.Type.String()
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.WriteByte(0xc0)
+		return nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			e.WriteByte(0xc0)
+			return nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.writeString(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.WriteByte(0xc3)
+		} else {
+			e.WriteByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.Float32:
+		e.WriteByte(0xca)
+		e.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(v.Float()))))
+	case reflect.Float64:
+		e.WriteByte(0xcb)
+		e.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+	case reflect.String:
+		e.writeString(v.String())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.WriteByte(0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.WriteByte(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.writeBinary(v.Bytes())
+			return nil
+		}
+		e.writeHeader(v.Len(), 0x90, 16, 0xdc, 0xdd)
+		for i := range v.Len() {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+// encodeMap writes a map with string keys, sorted so output is stable
+func (e *encoder) encodeMap(v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	if v.IsNil() {
+		e.WriteByte(0xc0)
+		return nil
+	}
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	e.writeHeader(len(keys), 0x80, 16, 0xde, 0xdf)
+	for _, key := range keys {
+		e.writeString(key.String())
+		if err := e.encode(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeStruct writes the exported fields as a map named by json tags
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	type field struct {
+		name  string
+		value reflect.Value
+	}
+	var fields []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fv := v.Field(i)
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+		fields = append(fields, field{name, fv})
+	}
+
+	e.writeHeader(len(fields), 0x80, 16, 0xde, 0xdf)
+	for _, f := range fields {
+		e.writeString(f.name)
+		if err := e.encode(f.value); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// writeHeader writes an array or map length: fixed up to fixMax-1, then
+// 16 or 32 bit
+func (e *encoder) writeHeader(n int, fix byte, fixMax int, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		e.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		e.WriteByte(code16)
+		e.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.WriteByte(code32)
+		e.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (e *encoder) writeString(s string) {
+	switch n := len(s); {
+	case n < 32:
+		e.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.WriteByte(0xd9)
+		e.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		e.WriteByte(0xda)
+		e.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.WriteByte(0xdb)
+		e.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	e.WriteString(s)
+}
+
+func (e *encoder) writeBinary(b []byte) {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		e.WriteByte(0xc4)
+		e.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		e.WriteByte(0xc5)
+		e.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.WriteByte(0xc6)
+		e.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	e.Write(b)
+}
+
+// writeInt uses the smallest encoding that holds i
+func (e *encoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		e.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16:
+		e.WriteByte(0xd1)
+		e.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= math.MinInt32:
+		e.WriteByte(0xd2)
+		e.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	default:
+		e.WriteByte(0xd3)
+		e.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func (e *encoder) writeUint(u uint64) {
+	switch {
+	case u <= math.MaxInt8:
+		e.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		e.Write([]byte{0xcc, byte(u)})
+	case u <= math.MaxUint16:
+		e.WriteByte(0xcd)
+		e.Write(binary.BigEndian.AppendUint16(nil, uint16(u)))
+	case u <= math.MaxUint32:
+		e.WriteByte(0xce)
+		e.Write(binary.BigEndian.AppendUint32(nil, uint32(u)))
+	default:
+		e.WriteByte(0xcf)
+		e.Write(binary.BigEndian.AppendUint64(nil, u))
+	}
+}
@@ -0,0 +1,93 @@
+package msgpack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	type inner struct {
+		Code string `json:"code"`
+	}
+	type record struct {
+		Name    string             `json:"name"`
+		Skipped string             `json:"-"`
+		Empty   string             `json:"empty,omitempty"`
+		Rates   map[string]float64 `json:"rates"`
+		Inner   *inner             `json:"inner,omitempty"`
+		hidden  int
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected []byte
+	}{
+		{"Nil", nil, []byte{0xc0}},
+		{"True", true, []byte{0xc3}},
+		{"Positive fixint", 7, []byte{0x07}},
+		{"Negative fixint", -3, []byte{0xfd}},
+		{"Uint8", 200, []byte{0xcc, 0xc8}},
+		{"Uint16", 1000, []byte{0xcd, 0x03, 0xe8}},
+		{"Int8", -100, []byte{0xd0, 0x9c}},
+		{"Int32", -100000, []byte{0xd2, 0xff, 0xfe, 0x79, 0x60}},
+		{"Float64", 0.5, []byte{0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		{"Fixstr", "EUR", []byte{0xa3, 'E', 'U', 'R'}},
+		{"Str8", strings.Repeat("a", 40), append([]byte{0xd9, 40}, strings.Repeat("a", 40)...)},
+		{"Fixarray", []string{"a", "b"}, []byte{0x92, 0xa1, 'a', 0xa1, 'b'}},
+		{"Nil slice", []int(nil), []byte{0xc0}},
+		{"Binary", []byte{1, 2}, []byte{0xc4, 2, 1, 2}},
+		{"Sorted map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 1, 0xa1, 'b', 2}},
+		{"Time as text", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			append([]byte{0xb4}, "2025-01-01T00:00:00Z"...)},
+		{"Struct uses json tags", record{Name: "x", Skipped: "y", Rates: map[string]float64{}, hidden: 1},
+			[]byte{0x82, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'x', 0xa5, 'r', 'a', 't', 'e', 's', 0x80}},
+		{"Nested pointer", record{Inner: &inner{Code: "A"}},
+			[]byte{0x83, 0xa4, 'n', 'a', 'm', 'e', 0xa0, 0xa5, 'r', 'a', 't', 'e', 's', 0xc0,
+				0xa5, 'i', 'n', 'n', 'e', 'r', 0x81, 0xa4, 'c', 'o', 'd', 'e', 0xa1, 'A'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tt.expected) {
+				t.Errorf("Expected % x, got % x", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMarshalLargeCollections(t *testing.T) {
+	got, err := Marshal(make([]bool, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(got, []byte{0xdc, 0x00, 0x14}) || len(got) != 23 {
+		t.Errorf("Expected an array16 of 20 elements, got % x", got)
+	}
+
+	m := make(map[string]int, 16)
+	for i := range 16 {
+		m[string(rune('a'+i))] = i
+	}
+	got, err = Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(got, []byte{0xde, 0x00, 0x10, 0xa1, 'a', 0x00}) {
+		t.Errorf("Expected a map16 starting with key a, got % x", got)
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	for _, v := range []any{make(chan int), map[int]string{1: "a"}, func() {}} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("Expected an error for %T", v)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type money struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+type Invoice struct {
+	ID       uint64            `json:"id"`
+	Total    money             `json:"total"`
+	Lines    []*Line           `json:"lines"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Paid     bool              `json:"paid"`
+	DueAt    time.Time         `json:"due_at"`
+	Note     string            `json:"-"`
+	Ratio    float32
+	Raw      []byte `json:"raw,omitempty"`
+	internal int
+}
+
+type Line struct {
+	Quantity int      `json:"quantity"`
+	Parent   *Invoice `json:"parent,omitempty"`
+	Extra    any      `json:"extra,omitempty"`
+}
+
+func TestSchema(t *testing.T) {
+	var c Components
+	ref := c.Schema(Invoice{})
+	if ref.Ref != "#/components/schemas/Invoice" {
+		t.Fatalf("Expected a reference to Invoice, got %+v", ref)
+	}
+
+	tests := []struct {
+		name     string
+		schema   string
+		expected string
+	}{
+		{"Invoice", "Invoice",
+			`{"type":"object","properties":{` +
+				`"Ratio":{"type":"number","format":"float"},` +
+				`"due_at":{"type":"string","format":"date-time"},` +
+				`"id":{"type":"integer","format":"int64"},` +
+				`"lines":{"type":"array","items":{"$ref":"#/components/schemas/Line"}},` +
+				`"paid":{"type":"boolean"},` +
+				`"raw":{"type":"string","format":"byte"},` +
+				`"tags":{"type":"object","additionalProperties":{"type":"string"}},` +
+				`"total":{"$ref":"#/components/schemas/money"}},` +
+				`"required":["id","total","lines","paid","due_at","Ratio"]}`},
+		{"Recursive reference", "Line",
+			`{"type":"object","properties":{` +
+				`"extra":{},` +
+				`"parent":{"$ref":"#/components/schemas/Invoice"},` +
+				`"quantity":{"type":"integer","format":"int64"}},` +
+				`"required":["quantity"]}`},
+		{"Nested struct", "money",
+			`{"type":"object","properties":{"amount":{"type":"string"},"currency":{"type":"string"}},"required":["currency","amount"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := c.Schemas[tt.schema]
+			if !ok {
+				t.Fatalf("Expected schema %s among %v", tt.schema, c.Schemas)
+			}
+			b, _ := json.Marshal(s)
+			if string(b) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+
+	if c.Resolve(ref) != c.Schemas["Invoice"] {
+		t.Errorf("Expected the reference to resolve to the Invoice schema")
+	}
+	if s := c.Schema(""); c.Resolve(s) != s {
+		t.Errorf("Expected an inline schema to resolve to itself")
+	}
+}
+
+func TestSchemaInline(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"Anonymous struct", struct {
+			N int8 `json:"n"`
+		}{}, `{"type":"object","properties":{"n":{"type":"integer","format":"int32"}},"required":["n"]}`},
+		{"Map of slices", map[string][]float64{}, `{"type":"object","additionalProperties":{"type":"array","items":{"type":"number","format":"double"}}}`},
+		{"Duration", time.Duration(0), `{"type":"integer","format":"int64"}`},
+		{"Time pointer", (*time.Time)(nil), `{"type":"string","format":"date-time"}`},
+		{"Nil", nil, `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Components
+			b, _ := json.Marshal(c.Schema(tt.value))
+			if string(b) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, b)
+			}
+			if len(c.Schemas) != 0 {
+				t.Errorf("Expected no component schemas, got %v", c.Schemas)
+			}
+		})
+	}
+}
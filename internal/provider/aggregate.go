@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsensusMethod selects how rates from several providers are combined
+type ConsensusMethod string
+
+const (
+	// Median takes the middle value of all reported rates
+	Median ConsensusMethod = "median"
+	// TrimmedMean drops the highest and lowest values before averaging
+	TrimmedMean ConsensusMethod = "trimmed_mean"
+)
+
+// Consensus describes how an aggregated rate table was produced
+type Consensus struct {
+	Method     ConsensusMethod              `json:"method"`
+	Providers  []string                     `json:"providers"`
+	Failed     map[string]string            `json:"failed,omitempty"`
+	Currencies map[string]CurrencyConsensus `json:"currencies"`
+}
+
+// CurrencyConsensus reports which providers quoted a currency and how far
+// apart their quotes were. Dispersion is the coefficient of variation
+// (standard deviation divided by mean) of the quotes.
+type CurrencyConsensus struct {
+	Providers  []string `json:"providers"`
+	Min        float64  `json:"min"`
+	Max        float64  `json:"max"`
+	Dispersion float64  `json:"dispersion"`
+}
+
+// Aggregator is a RateProvider that queries several providers concurrently
+// and returns a consensus rate per currency
+type Aggregator struct {
+	Providers []RateProvider
+	// Base is the currency every provider's table is rebased to
+	Base string
+	// Timeout bounds each provider fetch; zero means no extra timeout
+	Timeout time.Duration
+	Method  ConsensusMethod
+	// TrimPercent is the share of quotes dropped from each end for
+	// TrimmedMean, e.g. 20 drops the lowest and highest 20%
+	TrimPercent float64
+	// MinProviders is how many providers must succeed, at least one
+	MinProviders int
+}
+
+// NewAggregator creates a median aggregator over providers rebased to USD
+func NewAggregator(timeout time.Duration, providers ...RateProvider) *Aggregator {
+	return &Aggregator{
+		Providers:    providers,
+		Base:         "USD",
+		Timeout:      timeout,
+		Method:       Median,
+		TrimPercent:  20,
+		MinProviders: 1,
+	}
+}
+
+// Name returns a name derived from the underlying providers
+func (a *Aggregator) Name() string {
+	names := make([]string, len(a.Providers))
+	for i, p := range a.Providers {
+		names[i] = p.Name()
+	}
+	return "consensus(" + strings.Join(names, ",") + ")"
+}
+
+type fetchResult struct {
+	name  string
+	rates *Rates
+	err   error
+}
+
+// FetchRates queries every provider and combines the results
+func (a *Aggregator) FetchRates(ctx context.Context) (*Rates, error) {
+	if len(a.Providers) == 0 {
+		return nil, errors.New("no providers configured")
+	}
+
+	results := make([]fetchResult, len(a.Providers))
+	var wg sync.WaitGroup
+	for i, p := range a.Providers {
+		wg.Add(1)
+		go func(i int, p RateProvider) {
+			defer wg.Done()
+			fetchCtx := ctx
+			if a.Timeout > 0 {
+				var cancel context.CancelFunc
+				fetchCtx, cancel = context.WithTimeout(ctx, a.Timeout)
+				defer cancel()
+			}
+			rates, err := fetch(fetchCtx, p)
+			if err == nil {
+				rates, err = rebase(rates, a.Base)
+			}
+			results[i] = fetchResult{name: p.Name(), rates: rates, err: err}
+		}(i, p)
+	}
+	wg.Wait()
+
+	consensus := &Consensus{
+		Method:     a.Method,
+		Failed:     make(map[string]string),
+		Currencies: make(map[string]CurrencyConsensus),
+	}
+	quotes := make(map[string][]float64)
+	quoted := make(map[string][]string)
+	var asOf time.Time
+	for _, r := range results {
+		if r.err != nil {
+			consensus.Failed[r.name] = r.err.Error()
+			continue
+		}
+		consensus.Providers = append(consensus.Providers, r.name)
+		if asOf.IsZero() || r.rates.AsOf.Before(asOf) {
+			asOf = r.rates.AsOf
+		}
+		for code, rate := range r.rates.Rates {
+			quotes[code] = append(quotes[code], rate)
+			quoted[code] = append(quoted[code], r.name)
+		}
+	}
+
+	minProviders := a.MinProviders
+	if minProviders < 1 {
+		minProviders = 1
+	}
+	if len(consensus.Providers) < minProviders {
+		return nil, fmt.Errorf("only %d of %d providers succeeded, need %d", len(consensus.Providers), len(a.Providers), minProviders)
+	}
+
+	rates := make(map[string]float64, len(quotes))
+	for code, values := range quotes {
+		sort.Float64s(values)
+		if a.Method == TrimmedMean {
+			rates[code] = trimmedMean(values, a.TrimPercent)
+		} else {
+			rates[code] = median(values)
+		}
+		consensus.Currencies[code] = CurrencyConsensus{
+			Providers:  quoted[code],
+			Min:        values[0],
+			Max:        values[len(values)-1],
+			Dispersion: dispersion(values),
+		}
+	}
+	rates[a.Base] = 1.0
+
+	return &Rates{
+		Base:      a.Base,
+		Rates:     rates,
+		AsOf:      asOf,
+		Source:    a.Name(),
+		Consensus: consensus,
+	}, nil
+}
+
+// rebase converts a rate table so that base has a rate of 1. The table's
+// own base is kept even when the provider left it out, as many feeds do.
+func rebase(r *Rates, base string) (*Rates, error) {
+	if _, ok := r.Rates[base]; ok && r.Base == base {
+		return r, nil
+	}
+	pivot := 1.0
+	if r.Base != base {
+		var ok bool
+		if pivot, ok = r.Rates[base]; !ok || pivot <= 0 {
+			return nil, fmt.Errorf("cannot rebase %s table to %s", r.Base, base)
+		}
+	}
+	rebased := make(map[string]float64, len(r.Rates)+1)
+	for code, rate := range r.Rates {
+		rebased[code] = rate / pivot
+	}
+	if _, ok := rebased[r.Base]; !ok {
+		rebased[r.Base] = 1 / pivot
+	}
+	return &Rates{Base: base, Rates: rebased, AsOf: r.AsOf, Source: r.Source}, nil
+}
+
+// median expects sorted values
+func median(values []float64) float64 {
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// trimmedMean expects sorted values
+func trimmedMean(values []float64, percent float64) float64 {
+	trim := int(float64(len(values)) * percent / 100)
+	if 2*trim >= len(values) {
+		return median(values)
+	}
+	kept := values[trim : len(values)-trim]
+	var sum float64
+	for _, v := range kept {
+		sum += v
+	}
+	return sum / float64(len(kept))
+}
+
+func dispersion(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance/float64(len(values))) / mean
+}
@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingProvider struct{ name string }
+
+func (p failingProvider) Name() string { return p.name }
+
+func (p failingProvider) FetchRates(ctx context.Context) (*Rates, error) {
+	return nil, errors.New("feed unavailable")
+}
+
+type slowProvider struct{ name string }
+
+func (p slowProvider) Name() string { return p.name }
+
+func (p slowProvider) FetchRates(ctx context.Context) (*Rates, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Second):
+		return &Rates{Base: "USD", Rates: map[string]float64{"USD": 1, "EUR": 99}}, nil
+	}
+}
+
+func TestAggregatorConsensus(t *testing.T) {
+	a := NewStaticProvider("a", "USD", map[string]float64{"USD": 1, "EUR": 0.84, "GBP": 0.73})
+	b := NewStaticProvider("b", "USD", map[string]float64{"USD": 1, "EUR": 0.85, "GBP": 0.74})
+	c := NewStaticProvider("c", "USD", map[string]float64{"USD": 1, "EUR": 0.95})
+	// d quotes in EUR and must be rebased to USD
+	d := NewStaticProvider("d", "EUR", map[string]float64{"EUR": 1, "USD": 1 / 0.86})
+
+	tests := []struct {
+		name         string
+		method       ConsensusMethod
+		providers    []RateProvider
+		minProviders int
+		expectedEUR  float64
+		expectError  bool
+	}{
+		{
+			name:        "Median ignores outlier",
+			method:      Median,
+			providers:   []RateProvider{a, b, c},
+			expectedEUR: 0.85,
+		},
+		{
+			name:        "Median with rebased provider",
+			method:      Median,
+			providers:   []RateProvider{a, b, c, d},
+			expectedEUR: 0.855,
+		},
+		{
+			name:        "Trimmed mean drops extremes",
+			method:      TrimmedMean,
+			providers:   []RateProvider{a, b, c, d, NewStaticProvider("e", "USD", map[string]float64{"USD": 1, "EUR": 0.10})},
+			expectedEUR: (0.84 + 0.85 + 0.86) / 3,
+		},
+		{
+			name:        "Failed and slow providers are skipped",
+			method:      Median,
+			providers:   []RateProvider{a, failingProvider{"down"}, slowProvider{"slow"}},
+			expectedEUR: 0.84,
+		},
+		{
+			name:         "Quorum not met",
+			method:       Median,
+			providers:    []RateProvider{a, failingProvider{"down"}},
+			minProviders: 2,
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := NewAggregator(50*time.Millisecond, tt.providers...)
+			agg.Method = tt.method
+			if tt.minProviders > 0 {
+				agg.MinProviders = tt.minProviders
+			}
+
+			rates, err := agg.FetchRates(context.Background())
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := rates.Rates["EUR"]; math.Abs(got-tt.expectedEUR) > 1e-9 {
+				t.Errorf("Expected EUR consensus %.6f, got %.6f", tt.expectedEUR, got)
+			}
+			if rates.Rates["USD"] != 1.0 {
+				t.Errorf("Expected base USD rate 1, got %v", rates.Rates["USD"])
+			}
+			meta := rates.Consensus.Currencies["EUR"]
+			if len(meta.Providers) != len(rates.Consensus.Providers) {
+				t.Errorf("Expected every contributing provider to quote EUR, got %v", meta.Providers)
+			}
+			if len(rates.Consensus.Providers)+len(rates.Consensus.Failed) != len(tt.providers) {
+				t.Errorf("Expected every provider to be reported, got %v and %v", rates.Consensus.Providers, rates.Consensus.Failed)
+			}
+		})
+	}
+}
+
+func TestAggregatorDispersion(t *testing.T) {
+	a := NewStaticProvider("a", "USD", map[string]float64{"USD": 1, "EUR": 0.8})
+	b := NewStaticProvider("b", "USD", map[string]float64{"USD": 1, "EUR": 1.2})
+
+	rates, err := NewAggregator(0, a, b).FetchRates(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	meta := rates.Consensus.Currencies["EUR"]
+	if math.Abs(meta.Dispersion-0.2) > 1e-9 {
+		t.Errorf("Expected dispersion 0.2, got %v", meta.Dispersion)
+	}
+	if meta.Min != 0.8 || meta.Max != 1.2 {
+		t.Errorf("Expected min 0.8 and max 1.2, got %v and %v", meta.Min, meta.Max)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	tests := []struct {
+		name        string
+		rates       *Rates
+		expected    map[string]float64
+		expectError bool
+	}{
+		{
+			name:     "Same base",
+			rates:    &Rates{Base: "USD", Rates: map[string]float64{"USD": 1, "EUR": 0.8}},
+			expected: map[string]float64{"USD": 1, "EUR": 0.8},
+		},
+		{
+			name:     "Same base left out",
+			rates:    &Rates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}},
+			expected: map[string]float64{"USD": 1, "EUR": 0.8},
+		},
+		{
+			name:     "Other base",
+			rates:    &Rates{Base: "EUR", Rates: map[string]float64{"EUR": 1, "USD": 1.25, "GBP": 0.85}},
+			expected: map[string]float64{"USD": 1, "EUR": 0.8, "GBP": 0.68},
+		},
+		{
+			name:     "Other base left out",
+			rates:    &Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "GBP": 0.85}},
+			expected: map[string]float64{"USD": 1, "EUR": 0.8, "GBP": 0.68},
+		},
+		{
+			name:        "Base not quoted",
+			rates:       &Rates{Base: "EUR", Rates: map[string]float64{"GBP": 0.85}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rebased, err := rebase(tt.rates, "USD")
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if rebased.Base != "USD" || len(rebased.Rates) != len(tt.expected) {
+				t.Fatalf("Expected USD table %v, got %s %v", tt.expected, rebased.Base, rebased.Rates)
+			}
+			for code, rate := range tt.expected {
+				if got := rebased.Rates[code]; math.Abs(got-rate) > 1e-9 {
+					t.Errorf("Expected %s rate %v, got %v", code, rate, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "/unlisted-base":
+			w.Write([]byte(`{"base":"eur","rates":{"usd":1.25}}`))
+			return
+		}
+		w.Write([]byte(`{"base":"usd","rates":{"usd":1,"eur":0.85}}`))
+	}))
+	defer server.Close()
+
+	rates, err := NewHTTPProvider("remote", server.URL+"/rates", nil).FetchRates(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rates.Base != "USD" || rates.Rates["EUR"] != 0.85 {
+		t.Errorf("Expected normalised USD table, got %s %v", rates.Base, rates.Rates)
+	}
+
+	rates, err = NewHTTPProvider("remote", server.URL+"/unlisted-base", nil).FetchRates(context.Background())
+	if err != nil || rates.Rates["EUR"] != 1 {
+		t.Errorf("Expected the left out EUR base to be added, got %v, %v", rates, err)
+	}
+
+	if _, err := NewHTTPProvider("remote", server.URL+"/broken", nil).FetchRates(context.Background()); err == nil {
+		t.Errorf("Expected error for non-200 response")
+	}
+}
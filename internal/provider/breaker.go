@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects calls until the cooldown has passed
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe through to test recovery
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerConfig configures a CircuitBreaker
+type BreakerConfig struct {
+	// FailureThreshold is how many consecutive failures open the breaker
+	FailureThreshold int
+	// Cooldown is how long the breaker stays open before probing
+	Cooldown time.Duration
+	// HalfOpenSuccesses is how many successful probes close the breaker
+	HalfOpenSuccesses int
+}
+
+// DefaultBreakerConfig returns the breaker settings used when none are given
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold:  3,
+		Cooldown:          30 * time.Second,
+		HalfOpenSuccesses: 1,
+	}
+}
+
+// BreakerStatus is a point-in-time view of a breaker
+type BreakerStatus struct {
+	Provider            string       `json:"provider"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+}
+
+// CircuitBreaker stops calling a failing provider for a cooldown period
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	cfg       BreakerConfig
+	state     BreakerState
+	failures  int
+	successes int
+	probing   bool
+	lastErr   string
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+// NewCircuitBreaker creates a closed breaker
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.HalfOpenSuccesses < 1 {
+		cfg.HalfOpenSuccesses = 1
+	}
+	return &CircuitBreaker{cfg: cfg, state: BreakerClosed, now: time.Now}
+}
+
+// Allow reports whether a call may proceed. An open breaker moves to
+// half-open once the cooldown has passed and admits one probe at a time.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.Cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.successes = 0
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a successful call
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.lastErr = ""
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenSuccesses {
+			b.state = BreakerClosed
+		}
+	}
+}
+
+// Failure records a failed call, opening the breaker when the threshold
+// is reached or a half-open probe fails
+func (b *CircuitBreaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if err != nil {
+		b.lastErr = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) status(name string) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := BreakerStatus{
+		Provider:            name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastErr,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	return s
+}
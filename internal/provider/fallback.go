@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheSource is reported as the active source when every provider failed
+// and the last successful table is served instead
+const CacheSource = "cache"
+
+// StatusReporter is implemented by providers that can describe their
+// current health
+type StatusReporter interface {
+	Status() ProviderStatus
+}
+
+// ProviderStatus describes which source is serving rates and the state of
+// each provider's circuit breaker
+type ProviderStatus struct {
+	ActiveSource string          `json:"active_source"`
+	Breakers     []BreakerStatus `json:"breakers"`
+}
+
+// Fallback is a RateProvider that tries providers in order, skipping those
+// whose circuit breaker is open, and falls back to the last successfully
+// fetched table when all of them fail
+type Fallback struct {
+	// Base is the currency every provider's table is rebased to
+	Base string
+
+	providers []RateProvider
+	breakers  []*CircuitBreaker
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	cached *Rates
+	active string
+}
+
+// NewFallback creates a fallback chain rebased to USD with one breaker per
+// provider. timeout bounds each provider fetch; zero means no extra timeout.
+func NewFallback(cfg BreakerConfig, timeout time.Duration, providers ...RateProvider) *Fallback {
+	breakers := make([]*CircuitBreaker, len(providers))
+	for i := range providers {
+		breakers[i] = NewCircuitBreaker(cfg)
+	}
+	return &Fallback{Base: "USD", providers: providers, breakers: breakers, timeout: timeout}
+}
+
+// Name returns a name derived from the chained providers
+func (f *Fallback) Name() string {
+	names := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		names[i] = p.Name()
+	}
+	return "fallback(" + strings.Join(names, ",") + ")"
+}
+
+// FetchRates returns rates from the first available provider, rebased to
+// Base. A table that cannot be rebased counts as a failure. When every
+// provider fails the cached table is returned with Cached set.
+func (f *Fallback) FetchRates(ctx context.Context) (*Rates, error) {
+	var errs []error
+	for i, p := range f.providers {
+		breaker := f.breakers[i]
+		if !breaker.Allow() {
+			errs = append(errs, fmt.Errorf("%s: circuit open", p.Name()))
+			continue
+		}
+
+		rates, err := f.fetch(ctx, p)
+		if err == nil {
+			rates, err = rebase(rates, f.Base)
+		}
+		if err != nil {
+			breaker.Failure(err)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		breaker.Success()
+
+		f.mu.Lock()
+		f.cached = rates
+		f.active = p.Name()
+		f.mu.Unlock()
+		return rates, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cached == nil {
+		f.active = ""
+		return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+	}
+	f.active = CacheSource
+	cached := *f.cached
+	cached.Cached = true
+	return &cached, nil
+}
+
+func (f *Fallback) fetch(ctx context.Context, p RateProvider) (*Rates, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	return fetch(ctx, p)
+}
+
+// Status reports the active source and every breaker's state
+func (f *Fallback) Status() ProviderStatus {
+	f.mu.Lock()
+	active := f.active
+	f.mu.Unlock()
+
+	status := ProviderStatus{ActiveSource: active, Breakers: make([]BreakerStatus, len(f.providers))}
+	for i, p := range f.providers {
+		status.Breakers[i] = f.breakers[i].status(p.Name())
+	}
+	return status
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// toggleProvider fails while down is set
+type toggleProvider struct {
+	name  string
+	down  bool
+	calls int
+}
+
+func (p *toggleProvider) Name() string { return p.name }
+
+func (p *toggleProvider) FetchRates(ctx context.Context) (*Rates, error) {
+	p.calls++
+	if p.down {
+		return nil, errors.New("feed unavailable")
+	}
+	return &Rates{Base: "USD", Rates: map[string]float64{"USD": 1, "EUR": 0.85}, Source: p.name}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute, HalfOpenSuccesses: 1})
+	b.now = func() time.Time { return now }
+
+	b.Failure(errors.New("boom"))
+	if b.State() != BreakerClosed {
+		t.Fatalf("Expected breaker to stay closed below threshold, got %s", b.State())
+	}
+	b.Failure(errors.New("boom"))
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("Expected breaker to open and reject calls, got %s", b.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !b.Allow() {
+		t.Fatalf("Expected a half-open probe after cooldown")
+	}
+	if b.Allow() {
+		t.Errorf("Expected only one concurrent half-open probe")
+	}
+	b.Failure(errors.New("still down"))
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected failed probe to reopen breaker, got %s", b.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !b.Allow() {
+		t.Fatalf("Expected a half-open probe after second cooldown")
+	}
+	b.Success()
+	if b.State() != BreakerClosed {
+		t.Errorf("Expected successful probe to close breaker, got %s", b.State())
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	primary := &toggleProvider{name: "primary"}
+	secondary := &toggleProvider{name: "secondary"}
+	f := NewFallback(BreakerConfig{FailureThreshold: 1, Cooldown: time.Hour}, 0, primary, secondary)
+	ctx := context.Background()
+
+	rates, err := f.FetchRates(ctx)
+	if err != nil || rates.Source != "primary" {
+		t.Fatalf("Expected rates from primary, got %v, %v", rates, err)
+	}
+
+	primary.down = true
+	rates, err = f.FetchRates(ctx)
+	if err != nil || rates.Source != "secondary" {
+		t.Fatalf("Expected rates from secondary, got %v, %v", rates, err)
+	}
+
+	// The primary breaker is now open and must not be called again
+	calls := primary.calls
+	if _, err := f.FetchRates(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if primary.calls != calls {
+		t.Errorf("Expected open breaker to skip primary")
+	}
+
+	secondary.down = true
+	rates, err = f.FetchRates(ctx)
+	if err != nil || !rates.Cached {
+		t.Fatalf("Expected cached rates, got %v, %v", rates, err)
+	}
+
+	status := f.Status()
+	if status.ActiveSource != CacheSource {
+		t.Errorf("Expected active source %q, got %q", CacheSource, status.ActiveSource)
+	}
+	for _, b := range status.Breakers {
+		if b.State != BreakerOpen {
+			t.Errorf("Expected %s breaker to be open, got %s", b.Provider, b.State)
+		}
+	}
+}
+
+func TestFallbackRebases(t *testing.T) {
+	primary := &toggleProvider{name: "primary", down: true}
+	// Neither quotes its own base; the first cannot be rebased to USD
+	unusable := NewStaticProvider("unusable", "EUR", map[string]float64{"GBP": 0.85})
+	secondary := NewStaticProvider("secondary", "EUR", map[string]float64{"USD": 1.25, "GBP": 0.85})
+	f := NewFallback(DefaultBreakerConfig(), 0, primary, unusable, secondary)
+
+	rates, err := f.FetchRates(context.Background())
+	if err != nil || rates.Source != "secondary" {
+		t.Fatalf("Expected rates from secondary, got %v, %v", rates, err)
+	}
+	if rates.Base != "USD" || rates.Rates["USD"] != 1 || rates.Rates["EUR"] != 0.8 || math.Abs(rates.Rates["GBP"]-0.68) > 1e-9 {
+		t.Errorf("Expected the EUR table rebased to USD, got %s %v", rates.Base, rates.Rates)
+	}
+}
+
+func TestFallbackWithoutCache(t *testing.T) {
+	f := NewFallback(DefaultBreakerConfig(), 0, &toggleProvider{name: "primary", down: true})
+
+	if _, err := f.FetchRates(context.Background()); err == nil {
+		t.Errorf("Expected error when no provider succeeded and nothing is cached")
+	}
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"currency_go_microservice/internal/tracing"
+)
+
+// Rates is a rate table returned by a RateProvider. Cached is set when the
+// table is a previously fetched one served because every source failed.
+type Rates struct {
+	Base      string
+	Rates     map[string]float64
+	AsOf      time.Time
+	Source    string
+	Consensus *Consensus
+	Cached    bool
+}
+
+// RateProvider fetches the current exchange rates from a source
+type RateProvider interface {
+	Name() string
+	FetchRates(ctx context.Context) (*Rates, error)
+}
+
+// StaticProvider always returns the same rate table
+type StaticProvider struct {
+	name  string
+	base  string
+	rates map[string]float64
+}
+
+// NewStaticProvider creates a provider serving a fixed rate table
+func NewStaticProvider(name, base string, rates map[string]float64) *StaticProvider {
+	return &StaticProvider{name: name, base: strings.ToUpper(base), rates: normalise(rates)}
+}
+
+// Name returns the provider name
+func (p *StaticProvider) Name() string { return p.name }
+
+// FetchRates returns a copy of the configured table
+func (p *StaticProvider) FetchRates(ctx context.Context) (*Rates, error) {
+	return &Rates{Base: p.base, Rates: normalise(p.rates), AsOf: time.Now().UTC(), Source: p.name}, nil
+}
+
+// HTTPProvider fetches rates from a JSON endpoint returning
+// {"base": "USD", "rates": {"EUR": 0.85, ...}}, the same shape as /rates.
+// An optional "as_of" timestamp is used as the publication time.
+type HTTPProvider struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProvider creates a provider reading rates from url.
+// A nil client uses http.DefaultClient.
+func NewHTTPProvider(name, url string, client *http.Client) *HTTPProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPProvider{name: name, url: url, client: client}
+}
+
+// Name returns the provider name
+func (p *HTTPProvider) Name() string { return p.name }
+
+// FetchRates requests and decodes the remote rate table
+func (p *HTTPProvider) FetchRates(ctx context.Context) (*Rates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", p.name, resp.StatusCode)
+	}
+
+	var body struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+		AsOf  time.Time          `json:"as_of"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s returned invalid JSON: %w", p.name, err)
+	}
+	if body.Base == "" || len(body.Rates) == 0 {
+		return nil, fmt.Errorf("%s returned an empty rate table", p.name)
+	}
+
+	if body.AsOf.IsZero() {
+		body.AsOf = time.Now().UTC()
+	}
+
+	base, rates := strings.ToUpper(body.Base), normalise(body.Rates)
+	// Many feeds leave out their own base
+	if _, ok := rates[base]; !ok {
+		rates[base] = 1
+	}
+	return &Rates{
+		Base:   base,
+		Rates:  rates,
+		AsOf:   body.AsOf,
+		Source: p.name,
+	}, nil
+}
+
+// fetch calls p.FetchRates inside a span named after the provider
+func fetch(ctx context.Context, p RateProvider) (rates *Rates, err error) {
+	ctx, span := tracing.Start(ctx, "RateProvider.FetchRates", attribute.String("provider.name", p.Name()))
+	defer func() { tracing.End(span, err) }()
+	return p.FetchRates(ctx)
+}
+
+func normalise(rates map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		out[strings.ToUpper(code)] = rate
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/router"
+)
+
+// Plan is the set of limits applied to a caller
+type Plan struct {
+	// RequestsPerSecond is the sustained request rate
+	RequestsPerSecond float64
+	// Burst is how many requests may be made at once
+	Burst int
+	// DailyQuota caps conversions per UTC day; zero means unlimited
+	DailyQuota int
+}
+
+// Config assigns plans to callers. Authenticated clients use their entry in
+// Clients or DefaultPlan; unauthenticated callers are keyed by IP address
+// and use AnonymousPlan.
+type Config struct {
+	Plans         map[string]Plan
+	Clients       map[string]string
+	DefaultPlan   string
+	AnonymousPlan string
+}
+
+// Limiter enforces per-caller request rates and daily quotas
+type Limiter struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+}
+
+// New creates a limiter keeping its state in store
+func New(cfg Config, store Store) (*Limiter, error) {
+	for _, name := range []string{cfg.DefaultPlan, cfg.AnonymousPlan} {
+		if _, ok := cfg.Plans[name]; !ok {
+			return nil, fmt.Errorf("rate limit plan %q is not defined", name)
+		}
+	}
+	for client, name := range cfg.Clients {
+		if _, ok := cfg.Plans[name]; !ok {
+			return nil, fmt.Errorf("rate limit plan %q for client %s is not defined", name, client)
+		}
+	}
+	for name, p := range cfg.Plans {
+		if p.RequestsPerSecond <= 0 || p.Burst < 1 || p.DailyQuota < 0 {
+			return nil, fmt.Errorf("rate limit plan %q needs a positive rate and burst", name)
+		}
+	}
+	return &Limiter{cfg: cfg, store: store, now: time.Now}, nil
+}
+
+// caller returns the limiter key and plan for a request
+func (l *Limiter) caller(r *http.Request) (string, Plan) {
+	if id, ok := auth.FromContext(r.Context()); ok {
+		name, ok := l.cfg.Clients[id.ClientID]
+		if !ok {
+			name = l.cfg.DefaultPlan
+		}
+		return "client:" + id.ClientID, l.cfg.Plans[name]
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host, l.cfg.Plans[l.cfg.AnonymousPlan]
+}
+
+// TakeToken counts a request by the caller of r against its request rate.
+// ok is false when the store failed, in which case the request should be
+// let through.
+func (l *Limiter) TakeToken(r *http.Request) (result Result, ok bool) {
+	key, plan := l.caller(r)
+	result, err := l.store.TakeToken(r.Context(), key, plan.RequestsPerSecond, plan.Burst, l.now())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "rate limit store failed", "error", err)
+		return Result{}, false
+	}
+	return result, true
+}
+
+// ChargeQuota counts n conversions by the caller of r against its daily
+// quota, which resets at midnight UTC. More conversions than the quota
+// has left are rejected as a whole. ok is false when the plan has no quota
+// or the store failed, in which case the request should be let through.
+func (l *Limiter) ChargeQuota(r *http.Request, n int) (result Result, ok bool) {
+	key, plan := l.caller(r)
+	if plan.DailyQuota == 0 {
+		return Result{}, false
+	}
+	now := l.now().UTC()
+	resetAt := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	result, err := l.store.IncrementQuota(r.Context(), key, n, plan.DailyQuota, resetAt, now)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "quota store failed", "error", err)
+		return Result{}, false
+	}
+	return result, true
+}
+
+type costKey struct{}
+
+// WithCost returns a copy of ctx in which a request counts as n
+// conversions against the quota, e.g. the size of a batch
+func WithCost(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, costKey{}, n)
+}
+
+// Cost returns the number of conversions set with WithCost, or 1
+func Cost(ctx context.Context) int {
+	if n, ok := ctx.Value(costKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// RateLimitedMessage is the error message for requests over the rate limit
+const RateLimitedMessage = "Rate limit exceeded, retry later"
+
+// QuotaExceededMessage is the error message for requests over a daily
+// quota of limit conversions
+func QuotaExceededMessage(limit int) string {
+	return fmt.Sprintf("Daily quota of %d conversions exceeded", limit)
+}
+
+// RateLimit rejects callers exceeding their plan's request rate with 429.
+// It must run after authentication so clients are keyed by identity. If
+// the store fails the request is let through.
+func (l *Limiter) RateLimit(writeError router.ErrorWriter) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, ok := l.TakeToken(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w, "X-RateLimit-", result)
+			if !result.Allowed {
+				w.Header().Set("Retry-After", RetryAfter(result.RetryAfter))
+				writeError(w, r, http.StatusTooManyRequests, RateLimitedMessage)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Quota counts every request against the caller's daily quota, resetting at
+// midnight UTC, and rejects requests over it with 429. A request counts as
+// one conversion unless an earlier middleware set its cost with WithCost.
+func (l *Limiter) Quota(writeError router.ErrorWriter) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, ok := l.ChargeQuota(r, Cost(r.Context()))
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w, "X-Quota-", result)
+			if !result.Allowed {
+				w.Header().Set("Retry-After", RetryAfter(result.RetryAfter))
+				writeError(w, r, http.StatusTooManyRequests, QuotaExceededMessage(result.Limit))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeaders writes Limit, Remaining and Reset (Unix seconds) headers
+func setHeaders(w http.ResponseWriter, prefix string, result Result) {
+	h := w.Header()
+	h.Set(prefix+"Limit", strconv.Itoa(result.Limit))
+	h.Set(prefix+"Remaining", strconv.Itoa(result.Remaining))
+	h.Set(prefix+"Reset", strconv.FormatInt(int64(math.Ceil(float64(result.Reset.UnixNano())/1e9)), 10))
+}
+
+// RetryAfter formats d as a Retry-After value, rounded up to whole seconds
+func RetryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/auth"
+)
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func newLimiter(t *testing.T, store Store) (*Limiter, *time.Time) {
+	t.Helper()
+	l, err := New(Config{
+		Plans: map[string]Plan{
+			"anonymous": {RequestsPerSecond: 1, Burst: 2, DailyQuota: 3},
+			"standard":  {RequestsPerSecond: 10, Burst: 3},
+		},
+		DefaultPlan:   "standard",
+		AnonymousPlan: "anonymous",
+	}, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock := time.Date(2025, 1, 1, 23, 59, 0, 0, time.UTC)
+	l.now = func() time.Time { return clock }
+	return l, &clock
+}
+
+func request(ip, client string) *http.Request {
+	req := httptest.NewRequest("GET", "/exchange", nil)
+	req.RemoteAddr = ip + ":40000"
+	if client != "" {
+		req = req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{ClientID: client}))
+	}
+	return req
+}
+
+func TestRateLimit(t *testing.T) {
+	l, clock := newLimiter(t, NewMemoryStore())
+	handler := l.RateLimit(writeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name              string
+		ip                string
+		client            string
+		advance           time.Duration
+		expectedStatus    int
+		expectedRemaining string
+		expectedRetry     string
+	}{
+		{"First anonymous request", "10.0.0.1", "", 0, http.StatusOK, "1", ""},
+		{"Burst used up", "10.0.0.1", "", 0, http.StatusOK, "0", ""},
+		{"Over the limit", "10.0.0.1", "", 0, http.StatusTooManyRequests, "0", "1"},
+		{"Other IPs have their own bucket", "10.0.0.2", "", 0, http.StatusOK, "1", ""},
+		{"Refilled after a second", "10.0.0.1", "", time.Second, http.StatusOK, "0", ""},
+		{"Clients use their plan, not the IP", "10.0.0.1", "billing", 0, http.StatusOK, "2", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*clock = clock.Add(tt.advance)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request(tt.ip, tt.client))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("X-RateLimit-Remaining"); got != tt.expectedRemaining {
+				t.Errorf("Expected X-RateLimit-Remaining %s, got %s", tt.expectedRemaining, got)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tt.expectedRetry {
+				t.Errorf("Expected Retry-After %q, got %q", tt.expectedRetry, got)
+			}
+			if rr.Header().Get("X-RateLimit-Limit") == "" || rr.Header().Get("X-RateLimit-Reset") == "" {
+				t.Errorf("Expected X-RateLimit-Limit and X-RateLimit-Reset headers")
+			}
+		})
+	}
+}
+
+func TestDailyQuota(t *testing.T) {
+	l, clock := newLimiter(t, NewMemoryStore())
+	handler := l.Quota(writeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(client string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, request("10.0.0.1", client))
+		return rr
+	}
+
+	for i := 0; i < 3; i++ {
+		if rr := serve(""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected conversion %d within quota, got %d", i+1, rr.Code)
+		}
+	}
+	rr := serve("")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected quota to be exhausted, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After until midnight UTC (60), got %s", got)
+	}
+	if got := rr.Header().Get("X-Quota-Remaining"); got != "0" {
+		t.Errorf("Expected X-Quota-Remaining 0, got %s", got)
+	}
+
+	if rr := serve("billing"); rr.Code != http.StatusOK || rr.Header().Get("X-Quota-Limit") != "" {
+		t.Errorf("Expected unlimited plan to skip the quota, got %d", rr.Code)
+	}
+
+	*clock = clock.Add(time.Minute)
+	if rr := serve(""); rr.Code != http.StatusOK || rr.Header().Get("X-Quota-Remaining") != "2" {
+		t.Errorf("Expected quota to reset at midnight UTC, got %d with %s remaining", rr.Code, rr.Header().Get("X-Quota-Remaining"))
+	}
+}
+
+func TestQuotaCost(t *testing.T) {
+	l, _ := newLimiter(t, NewMemoryStore())
+	handler := l.Quota(writeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name              string
+		cost              int
+		expectedStatus    int
+		expectedRemaining string
+	}{
+		{"Batch of two", 2, http.StatusOK, "1"},
+		{"Batch over what is left", 2, http.StatusTooManyRequests, "1"},
+		{"No cost counts as one", 0, http.StatusOK, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := request("10.0.0.1", "")
+			if tt.cost != 0 {
+				req = req.WithContext(WithCost(req.Context(), tt.cost))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("X-Quota-Remaining"); got != tt.expectedRemaining {
+				t.Errorf("Expected X-Quota-Remaining %s, got %s", tt.expectedRemaining, got)
+			}
+		})
+	}
+}
+
+// failingStore is a Store whose backend is down
+type failingStore struct{}
+
+func (failingStore) TakeToken(context.Context, string, float64, int, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func (failingStore) IncrementQuota(context.Context, string, int, int, time.Time, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestStoreFailureLetsRequestsThrough(t *testing.T) {
+	l, _ := newLimiter(t, failingStore{})
+	handler := l.RateLimit(writeError)(l.Quota(writeError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, request("10.0.0.1", ""))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected request to be served when the store fails, got %d", rr.Code)
+	}
+}
+
+func TestNewRejectsUndefinedPlans(t *testing.T) {
+	_, err := New(Config{
+		Plans:         map[string]Plan{"standard": {RequestsPerSecond: 1, Burst: 1}},
+		Clients:       map[string]string{"billing": "premium"},
+		DefaultPlan:   "standard",
+		AnonymousPlan: "standard",
+	}, NewMemoryStore())
+	if err == nil {
+		t.Errorf("Expected error for undefined client plan")
+	}
+}
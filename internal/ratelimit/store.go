@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Result describes a limit after a request was counted against it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the limit is fully available again
+	Reset time.Time
+	// RetryAfter is how long a rejected caller should wait
+	RetryAfter time.Duration
+}
+
+// Store keeps limiter state. MemoryStore keeps it per process; a shared
+// implementation (e.g. Redis) makes limits apply across replicas.
+type Store interface {
+	// TakeToken takes one token from the bucket for key, which refills at
+	// rate tokens per second up to burst
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (Result, error)
+	// IncrementQuota counts n uses against key's quota of limit uses,
+	// which resets at resetAt. Uses that would exceed the quota are
+	// rejected and not counted.
+	IncrementQuota(ctx context.Context, key string, n, limit int, resetAt, now time.Time) (Result, error)
+}
+
+// sweepEvery is how many calls pass between removals of idle entries
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+type quota struct {
+	used    int
+	resetAt time.Time
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	quotas  map[string]*quota
+	calls   int
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), quotas: make(map[string]*quota)}
+}
+
+// TakeToken implements Store
+func (s *MemoryStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = b
+	}
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.updated = now
+	}
+
+	result := Result{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	result.Reset = b.full
+	return result, nil
+}
+
+// IncrementQuota implements Store
+func (s *MemoryStore) IncrementQuota(ctx context.Context, key string, n, limit int, resetAt, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	q, ok := s.quotas[key]
+	if !ok || !now.Before(q.resetAt) {
+		q = &quota{resetAt: resetAt}
+		s.quotas[key] = q
+	}
+
+	result := Result{Limit: limit, Reset: q.resetAt}
+	if q.used+n <= limit {
+		q.used += n
+		result.Allowed = true
+	} else {
+		result.RetryAfter = q.resetAt.Sub(now)
+	}
+	result.Remaining = limit - q.used
+	return result, nil
+}
+
+// sweep drops buckets that have refilled and quotas that have reset, so
+// one-off callers do not accumulate
+func (s *MemoryStore) sweep(now time.Time) {
+	s.calls++
+	if s.calls%sweepEvery != 0 {
+		return
+	}
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, q := range s.quotas {
+		if !now.Before(q.resetAt) {
+			delete(s.quotas, key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// JSON sets the application/json Content-Type before the handler runs
+func JSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// headerTracker records whether the response headers have been sent
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(code int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Recover turns a panicking handler into a logged 500 response written
+// with writeError, so the connection is not dropped. If the handler had
+// already started the response only the log line is written.
+// http.ErrAbortHandler is re-panicked as net/http expects.
+func Recover(writeError ErrorWriter) Middleware {
+	if writeError == nil {
+		writeError = defaultErrorWriter
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tracker := &headerTracker{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				slog.ErrorContext(r.Context(), "handler panicked",
+					"panic", v, "method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
+				if !tracker.wroteHeader {
+					writeError(w, r, http.StatusInternalServerError, "Internal server error")
+				}
+			}()
+			next.ServeHTTP(tracker, r)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Middleware wraps a handler with cross-cutting behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middleware so the first one is the outermost
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		return h
+	}
+}
+
+// ErrorWriter writes an error response for status with message
+type ErrorWriter func(w http.ResponseWriter, r *http.Request, status int, message string)
+
+// Route is a registered method and path pattern
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// Router dispatches requests by path pattern and method. Patterns use the
+// http.ServeMux syntax without a method, e.g. "/snapshots/{id}/activate".
+// Requests whose method has no handler get 405 with an Allow header.
+type Router struct {
+	mux        *http.ServeMux
+	paths      map[string]map[string]http.Handler
+	noHead     map[string]bool
+	routes     []Route
+	middleware []Middleware
+	writeError ErrorWriter
+
+	once    sync.Once
+	handler http.Handler
+}
+
+// New creates a router writing 404 and 405 responses with writeError. A nil
+// writeError sends {"error": message} as JSON.
+func New(writeError ErrorWriter) *Router {
+	if writeError == nil {
+		writeError = defaultErrorWriter
+	}
+	rt := &Router{
+		mux:        http.NewServeMux(),
+		paths:      make(map[string]map[string]http.Handler),
+		noHead:     make(map[string]bool),
+		writeError: writeError,
+	}
+	rt.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		rt.writeError(w, r, http.StatusNotFound, "Not found")
+	})
+	return rt
+}
+
+func defaultErrorWriter(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// Use appends middleware run for every request, including unmatched ones.
+// It must be called before the router serves its first request.
+func (rt *Router) Use(mws ...Middleware) {
+	rt.middleware = append(rt.middleware, mws...)
+}
+
+// Handle registers h for method requests to pattern, wrapped in the
+// route-specific middleware mws. GET routes also answer HEAD unless the
+// pattern is passed to NoHead.
+func (rt *Router) Handle(method, pattern string, h http.Handler, mws ...Middleware) {
+	methods, ok := rt.paths[pattern]
+	if !ok {
+		methods = make(map[string]http.Handler)
+		rt.paths[pattern] = methods
+		rt.mux.Handle(pattern, rt.dispatch(pattern, methods))
+	}
+	if _, exists := methods[method]; exists {
+		panic(fmt.Sprintf("router: duplicate route %s %s", method, pattern))
+	}
+	methods[method] = Chain(mws...)(h)
+	rt.routes = append(rt.routes, Route{Method: method, Pattern: pattern})
+}
+
+// HandleFunc registers a handler function, see Handle
+func (rt *Router) HandleFunc(method, pattern string, h http.HandlerFunc, mws ...Middleware) {
+	rt.Handle(method, pattern, h, mws...)
+}
+
+// NoHead stops GET routes at the patterns from answering HEAD, which then
+// gets 405. Streams need it: their GET handler never finishes a response.
+// It must be called before the router serves its first request.
+func (rt *Router) NoHead(patterns ...string) {
+	for _, pattern := range patterns {
+		rt.noHead[pattern] = true
+	}
+}
+
+// Routes lists the registered routes in registration order
+func (rt *Router) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+func (rt *Router) dispatch(pattern string, methods map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := methods[r.Method]
+		if !ok && r.Method == http.MethodHead && !rt.noHead[pattern] {
+			h, ok = methods[http.MethodGet]
+		}
+		if ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := allowedMethods(methods, !rt.noHead[pattern])
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		rt.writeError(w, r, http.StatusMethodNotAllowed, methodNotAllowedMessage(allowed))
+	})
+}
+
+// allowedMethods lists the methods a path answers, adding HEAD for GET
+// when implicitHead is set
+func allowedMethods(methods map[string]http.Handler, implicitHead bool) []string {
+	allowed := make([]string, 0, len(methods)+1)
+	for m := range methods {
+		allowed = append(allowed, m)
+	}
+	if _, ok := methods[http.MethodGet]; ok && implicitHead {
+		if _, ok := methods[http.MethodHead]; !ok {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// methodNotAllowedMessage names the methods a client may use instead,
+// leaving out the implicit HEAD
+func methodNotAllowedMessage(allowed []string) string {
+	var explicit []string
+	for _, m := range allowed {
+		if m != http.MethodHead || len(allowed) == 1 {
+			explicit = append(explicit, m)
+		}
+	}
+	if len(explicit) == 1 {
+		return fmt.Sprintf("Only %s method is allowed", explicit[0])
+	}
+	return fmt.Sprintf("Only %s methods are allowed", strings.Join(explicit, ", "))
+}
+
+// ServeHTTP runs the global middleware and dispatches the request
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.once.Do(func() { rt.handler = Chain(rt.middleware...)(rt.mux) })
+	rt.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterDispatch(t *testing.T) {
+	rt := New(nil)
+	rt.HandleFunc(http.MethodGet, "/items", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "list") })
+	rt.HandleFunc(http.MethodPost, "/items", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "create") })
+	rt.HandleFunc(http.MethodPost, "/items/{id}/archive", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "archive "+r.PathValue("id"))
+	})
+	rt.HandleFunc(http.MethodGet, "/stream", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "stream") })
+	rt.NoHead("/stream")
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		expectedBody   string
+		expectedAllow  string
+	}{
+		{"GET route", "GET", "/items", http.StatusOK, "list", ""},
+		{"POST route on same path", "POST", "/items", http.StatusOK, "create", ""},
+		{"HEAD falls back to GET", "HEAD", "/items", http.StatusOK, "list", ""},
+		{"Path parameter", "POST", "/items/7/archive", http.StatusOK, "archive 7", ""},
+		{"Method not allowed", "DELETE", "/items", http.StatusMethodNotAllowed, `{"error":"Only GET, POST methods are allowed"}`, "GET, HEAD, POST"},
+		{"Single method not allowed", "GET", "/items/7/archive", http.StatusMethodNotAllowed, `{"error":"Only POST method is allowed"}`, "POST"},
+		{"HEAD opted out", "HEAD", "/stream", http.StatusMethodNotAllowed, `{"error":"Only GET method is allowed"}`, "GET"},
+		{"GET of opted out route", "GET", "/stream", http.StatusOK, "stream", ""},
+		{"Unknown path", "GET", "/nowhere", http.StatusNotFound, `{"error":"Not found"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+			if allow := rr.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.expectedAllow, allow)
+			}
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	rt := New(nil)
+	rt.Use(trace("outer"), trace("inner"))
+	rt.HandleFunc(http.MethodGet, "/admin", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}, trace("route"))
+
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin", nil))
+	if got := strings.Join(calls, ","); got != "outer,inner,route,handler" {
+		t.Errorf("Expected outer,inner,route,handler, got %s", got)
+	}
+
+	calls = nil
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/admin", nil))
+	if got := strings.Join(calls, ","); got != "outer,inner" {
+		t.Errorf("Expected route middleware to be skipped on 405, got %s", got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var written []string
+	writeError := func(w http.ResponseWriter, r *http.Request, status int, message string) {
+		written = append(written, message)
+		defaultErrorWriter(w, r, status, message)
+	}
+
+	rt := New(writeError)
+	rt.Use(Recover(writeError))
+	rt.HandleFunc(http.MethodGet, "/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	rt.HandleFunc(http.MethodGet, "/partial", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late boom")
+	})
+
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, httptest.NewRequest("GET", "/panic", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code 500, got %d", rr.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body["error"] != "Internal server error" {
+		t.Errorf("Expected JSON error response, got %q", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, httptest.NewRequest("GET", "/partial", nil))
+	if rr.Code != http.StatusAccepted || len(written) != 1 {
+		t.Errorf("Expected no error response after headers were sent, got %d and %d error writes", rr.Code, len(written))
+	}
+}
+
+func TestDuplicateRoutePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected duplicate route to panic")
+		}
+	}()
+	rt := New(nil)
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	rt.HandleFunc(http.MethodGet, "/items", h)
+	rt.HandleFunc(http.MethodGet, "/items", h)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(DefaultConfig(), handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, srv, ln, 5*time.Second) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		resCh <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-resCh
+	if res.err != nil || res.body != "done" {
+		t.Errorf("Expected in-flight request to complete, got %q, %v", res.body, res.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+
+	if _, err := http.Get("http://" + ln.Addr().String() + "/"); err == nil {
+		t.Errorf("Expected new connections to be refused after shutdown")
+	}
+}
+
+func TestNewAppliesConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Addr = "127.0.0.1:9999"
+	cfg.MaxHeaderBytes = 4096
+
+	srv := New(cfg, nil)
+	if srv.Addr != cfg.Addr || srv.MaxHeaderBytes != 4096 || srv.ReadTimeout != cfg.ReadTimeout ||
+		srv.WriteTimeout != cfg.WriteTimeout || srv.IdleTimeout != cfg.IdleTimeout {
+		t.Errorf("Expected server to use config %+v, got %+v", cfg, srv)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client certificate policies for mutual TLS
+const (
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// TLSConfig holds the native TLS settings. TLS is enabled when CertFile and
+// KeyFile are set; ClientCAFile additionally verifies client certificates.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	// ClientAuth is "require" to reject clients without a certificate, or
+	// "optional" to verify certificates only when presented
+	ClientAuth string
+	// ReloadInterval is how often the certificate files are checked for
+	// changes
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether TLS is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// CertReloader serves a certificate from disk and reloads it when the files
+// change, so renewed certificates are picked up without a restart
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+// NewCertReloader loads the key pair from certFile and keyFile
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads the key pair again if either file changed since the last
+// load. On error the current certificate stays in use.
+func (r *CertReloader) Reload() (bool, error) {
+	modified, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && modified.Equal(r.modified)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modified = modified
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch checks for changed files every interval until ctx is cancelled
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				slog.ErrorContext(ctx, "TLS certificate reload failed, keeping current certificate", "error", err)
+			} else if reloaded {
+				slog.InfoContext(ctx, "TLS certificate reloaded", "cert_file", r.certFile)
+			}
+		}
+	}
+}
+
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// ConfigureTLS enables TLS on srv using cfg and reloads the certificate in
+// the background until ctx is cancelled
+func ConfigureTLS(ctx context.Context, srv *http.Server, cfg TLSConfig) error {
+	reloader, err := NewCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client CA file contains no PEM certificates")
+		}
+		tlsConfig.ClientCAs = pool
+		switch cfg.ClientAuth {
+		case ClientAuthRequire:
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		case ClientAuthOptional, "":
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return fmt.Errorf("unknown client auth policy %q", cfg.ClientAuth)
+		}
+	}
+
+	srv.TLSConfig = tlsConfig
+	if cfg.ReloadInterval > 0 {
+		go reloader.Watch(ctx, cfg.ReloadInterval)
+	}
+	return nil
+}
@@ -1,12 +1,26 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"currency_go_microservice/internal/metrics"
+	"currency_go_microservice/internal/provider"
+	"currency_go_microservice/internal/ratelimit"
+	"currency_go_microservice/internal/tracing"
 )
 
 // ExchangeRates holds the conversion rates from USD to other currencies
@@ -32,36 +46,249 @@ type ExchangeRequest struct {
 
 // ExchangeResponse represents the response structure
 type ExchangeResponse struct {
-	From            string  `json:"from"`
-	To              string  `json:"to"`
-	Amount          float64 `json:"amount"`
-	ConvertedAmount float64 `json:"converted_amount"`
-	Rate            float64 `json:"rate"`
+	XMLName         xml.Name  `json:"-" xml:"exchange"`
+	From            string    `json:"from" xml:"from"`
+	To              string    `json:"to" xml:"to"`
+	Amount          float64   `json:"amount" xml:"amount"`
+	ConvertedAmount float64   `json:"converted_amount" xml:"converted_amount"`
+	Rate            float64   `json:"rate" xml:"rate"`
+	Version         uint64    `json:"version" xml:"version"`
+	AsOf            time.Time `json:"as_of" xml:"as_of"`
+	Source          string    `json:"source" xml:"source"`
+	Warning         string    `json:"warning,omitempty" xml:"warning,omitempty"`
+}
+
+// exchangeCSVHeader names the columns of conversions written as CSV
+var exchangeCSVHeader = []string{"from", "to", "amount", "converted_amount", "rate", "version", "as_of", "source", "warning"}
+
+func (e *ExchangeResponse) csvRecord() []string {
+	return []string{
+		e.From, e.To, formatFloat(e.Amount), formatFloat(e.ConvertedAmount), formatFloat(e.Rate),
+		strconv.FormatUint(e.Version, 10), e.AsOf.Format(time.RFC3339Nano), e.Source, e.Warning,
+	}
+}
+
+func (e *ExchangeResponse) csvRecords() [][]string {
+	return [][]string{exchangeCSVHeader, e.csvRecord()}
+}
+
+// BatchRequest is the body of a batch conversion
+type BatchRequest struct {
+	Conversions []ExchangeRequest `json:"conversions"`
+}
+
+// BatchResponse lists batch results in request order
+type BatchResponse struct {
+	XMLName xml.Name      `json:"-" xml:"batch"`
+	Results []BatchResult `json:"results" xml:"result"`
+}
+
+// BatchResult is one entry of a BatchResponse: a conversion or the error
+// that stopped it
+type BatchResult struct {
+	Conversion *ExchangeResponse `json:"conversion,omitempty" xml:"exchange,omitempty"`
+	Error      *BatchError       `json:"error,omitempty" xml:"error,omitempty"`
+	request    ExchangeRequest
+}
+
+// BatchError describes a failed batch entry with the code and field an
+// ErrorResponse would carry
+type BatchError struct {
+	Code    string `json:"code" xml:"code"`
+	Field   string `json:"field,omitempty" xml:"field,omitempty"`
+	Message string `json:"message" xml:"message"`
+}
+
+// csvRecords writes one row per result; failed rows repeat the request
+// and fill only the error column
+func (b *BatchResponse) csvRecords() [][]string {
+	records := [][]string{append(exchangeCSVHeader[:len(exchangeCSVHeader):len(exchangeCSVHeader)], "error_code", "error")}
+	for _, result := range b.Results {
+		if result.Conversion != nil {
+			records = append(records, append(result.Conversion.csvRecord(), "", ""))
+			continue
+		}
+		row := make([]string, len(exchangeCSVHeader)+2)
+		row[0], row[1], row[2] = result.request.From, result.request.To, formatFloat(result.request.Amount)
+		row[len(row)-2], row[len(row)-1] = result.Error.Code, result.Error.Message
+		records = append(records, row)
+	}
+	return records
 }
 
-// ErrorResponse represents error response structure
-type ErrorResponse struct {
-	Error string `json:"error"`
+// RatesResponse is the rate table served by /rates
+type RatesResponse struct {
+	XMLName  xml.Name            `json:"-" xml:"exchange_rates"`
+	Base     string              `json:"base" xml:"base"`
+	Rates    RateTable           `json:"rates" xml:"rates"`
+	Version  uint64              `json:"version" xml:"version"`
+	AsOf     time.Time           `json:"as_of" xml:"as_of"`
+	Source   string              `json:"source" xml:"source"`
+	Warning  string              `json:"warning,omitempty" xml:"warning,omitempty"`
+	Metadata *provider.Consensus `json:"metadata,omitempty" xml:"-"`
+}
+
+// csvRecords writes one row per currency, sorted by code
+func (r *RatesResponse) csvRecords() [][]string {
+	records := [][]string{{"base", "currency", "rate", "version", "as_of", "source", "warning"}}
+	for _, code := range r.Rates.codes() {
+		records = append(records, []string{
+			r.Base, code, formatFloat(r.Rates[code]),
+			strconv.FormatUint(r.Version, 10), r.AsOf.Format(time.RFC3339Nano), r.Source, r.Warning,
+		})
+	}
+	return records
+}
+
+// RateTable maps currency codes to rates. In XML it is a list of
+// <rate currency="EUR">0.85</rate> elements.
+type RateTable map[string]float64
+
+func (t RateTable) codes() []string {
+	codes := make([]string, 0, len(t))
+	for code := range t {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// MarshalXML implements xml.Marshaler
+func (t RateTable) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, code := range t.codes() {
+		rate := xml.StartElement{
+			Name: xml.Name{Local: "rate"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "currency"}, Value: code}},
+		}
+		if err := e.EncodeElement(formatFloat(t[code]), rate); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MaxBatchSize is the most conversions accepted in one batch
+const MaxBatchSize = 100
+
+// ExchangeResult is the outcome of one conversion in a batch. Exactly one
+// of Response and Err is set.
+type ExchangeResult struct {
+	Response *ExchangeResponse
+	Err      error
 }
 
 // CurrencyService handles currency exchange operations
-type CurrencyService struct{}
+type CurrencyService struct {
+	snapshots    snapshotHolder
+	store        SnapshotStore
+	provider     provider.RateProvider
+	guard        *AnomalyGuard
+	onQuarantine func(QuarantinedSnapshot)
+	maxAge       time.Duration
+	stalePolicy  StalePolicy
+	now          func() time.Time
+	registry     *metrics.Registry
+	metrics      *serviceMetrics
+	watchers     watchers
+	streams      streams
+}
 
-// NewCurrencyService creates a new currency service instance
-func NewCurrencyService() *CurrencyService {
-	return &CurrencyService{}
+// Option configures a CurrencyService
+type Option func(*CurrencyService)
+
+// WithAnomalyGuard replaces the default guard applied to new snapshots.
+// A nil guard disables validation.
+func WithAnomalyGuard(g *AnomalyGuard) Option {
+	return func(cs *CurrencyService) { cs.guard = g }
+}
+
+// WithQuarantineAlert registers a callback invoked whenever a snapshot is
+// quarantined
+func WithQuarantineAlert(fn func(QuarantinedSnapshot)) Option {
+	return func(cs *CurrencyService) { cs.onQuarantine = fn }
+}
+
+// WithSnapshotStore sets where installed snapshots are kept for rollback
+func WithSnapshotStore(store SnapshotStore) Option {
+	return func(cs *CurrencyService) { cs.store = store }
 }
 
-// ConvertCurrency performs the currency conversion
+// NewCurrencyService creates a new currency service instance. The latest
+// snapshot in the store is activated; an empty store is seeded with the
+// built-in ExchangeRates table.
+func NewCurrencyService(opts ...Option) *CurrencyService {
+	cs := &CurrencyService{
+		store: NewMemorySnapshotStore(50),
+		guard: DefaultAnomalyGuard(),
+		now:   time.Now,
+		streams: streams{
+			opts:    DefaultStreamOptions(),
+			clients: make(map[string]int),
+			done:    make(chan struct{}),
+		},
+	}
+	for _, opt := range opts {
+		opt(cs)
+	}
+	if cs.registry == nil {
+		cs.registry = metrics.NewRegistry()
+	}
+	cs.metrics = newServiceMetrics(cs.registry, cs)
+
+	if existing, err := cs.listSnapshots(context.Background()); err == nil && len(existing) > 0 {
+		latest := existing[len(existing)-1]
+		cs.snapshots.active = latest
+		cs.snapshots.version = latest.Version
+		return cs
+	}
+
+	seed := NewRateSnapshot("USD", ExchangeRates)
+	seed.Version = 1
+	seed.Source = "builtin"
+	seed.InstalledAt = cs.now().UTC()
+	seed.AsOf = seed.InstalledAt
+	if err := cs.saveSnapshot(context.Background(), seed); err != nil {
+		slog.Error("failed to store seed snapshot", "error", err)
+	}
+	cs.snapshots.active = seed
+	cs.snapshots.version = seed.Version
+	return cs
+}
+
+// ConvertCurrency performs the currency conversion. Errors are
+// ErrInvalidAmount or an *UnsupportedCurrencyError.
 func (cs *CurrencyService) ConvertCurrency(from, to string, amount float64) (float64, float64, error) {
-	fromRate, fromExists := ExchangeRates[strings.ToUpper(from)]
-	toRate, toExists := ExchangeRates[strings.ToUpper(to)]
+	return cs.convert(context.Background(), cs.ActiveSnapshot(), from, to, amount)
+}
+
+// convert performs the conversion against a specific snapshot inside a
+// ConvertCurrency span
+func (cs *CurrencyService) convert(ctx context.Context, snapshot *RateSnapshot, from, to string, amount float64) (converted, rate float64, err error) {
+	_, span := tracing.Start(ctx, "ConvertCurrency",
+		attribute.String("currency.from", strings.ToUpper(from)),
+		attribute.String("currency.to", strings.ToUpper(to)),
+		attribute.Int64("snapshot.version", int64(snapshot.Version)))
+	defer func() { tracing.End(span, err) }()
+	return convertAt(snapshot, from, to, amount)
+}
+
+// convertAt converts using the rates of a specific snapshot
+func convertAt(snapshot *RateSnapshot, from, to string, amount float64) (float64, float64, error) {
+	if !(amount > 0) || math.IsInf(amount, 1) {
+		return 0, 0, ErrInvalidAmount
+	}
+	rates := snapshot.Rates
+	fromRate, fromExists := rates[strings.ToUpper(from)]
+	toRate, toExists := rates[strings.ToUpper(to)]
 
 	if !fromExists {
-		return 0, 0, fmt.Errorf("currency %s not supported", from)
+		return 0, 0, &UnsupportedCurrencyError{Currency: from, Field: "from"}
 	}
 	if !toExists {
-		return 0, 0, fmt.Errorf("currency %s not supported", to)
+		return 0, 0, &UnsupportedCurrencyError{Currency: to, Field: "to"}
 	}
 
 	// Convert to USD first, then to target currency
@@ -72,68 +299,273 @@ func (cs *CurrencyService) ConvertCurrency(from, to string, amount float64) (flo
 	return convertedAmount, rate, nil
 }
 
+// Exchange converts amount between two currencies at the active snapshot,
+// applying the stale policy. Errors are a *RequestError for a missing
+// currency, ErrInvalidAmount, an *UnsupportedCurrencyError or wrap
+// ErrRatesStale.
+func (cs *CurrencyService) Exchange(ctx context.Context, from, to string, amount float64) (*ExchangeResponse, error) {
+	if err := requireCurrencies(from, to); err != nil {
+		return nil, err
+	}
+	snapshot := cs.ActiveSnapshot()
+	if err := cs.checkStale(snapshot); err != nil {
+		return nil, err
+	}
+	return cs.exchangeAt(ctx, snapshot, from, to, amount)
+}
+
+// ExchangeBatch converts up to MaxBatchSize requests against the same
+// snapshot, so all results share one version. Entries fail on their own,
+// as Exchange would. The whole batch fails with a *RequestError when it is
+// empty or too large, and with stale rates under StaleReject.
+func (cs *CurrencyService) ExchangeBatch(ctx context.Context, reqs []ExchangeRequest) ([]ExchangeResult, error) {
+	if len(reqs) == 0 {
+		return nil, missingParameter("conversions")
+	}
+	if len(reqs) > MaxBatchSize {
+		return nil, &RequestError{Code: CodeBatchTooLarge, Field: "conversions",
+			Message: fmt.Sprintf("At most %d conversions are allowed per batch", MaxBatchSize)}
+	}
+	snapshot := cs.ActiveSnapshot()
+	if err := cs.checkStale(snapshot); err != nil {
+		return nil, err
+	}
+	results := make([]ExchangeResult, len(reqs))
+	for i, req := range reqs {
+		if results[i].Err = requireCurrencies(req.From, req.To); results[i].Err == nil {
+			results[i].Response, results[i].Err = cs.exchangeAt(ctx, snapshot, req.From, req.To, req.Amount)
+		}
+	}
+	return results, nil
+}
+
+// requireCurrencies reports a missing from or to currency
+func requireCurrencies(from, to string) error {
+	switch {
+	case from == "":
+		return missingParameter("from")
+	case to == "":
+		return missingParameter("to")
+	}
+	return nil
+}
+
+// checkStale refuses stale snapshots under StaleReject
+func (cs *CurrencyService) checkStale(snapshot *RateSnapshot) error {
+	if cs.stalePolicy == StaleReject && cs.IsStale(snapshot) {
+		return fmt.Errorf("%w: %s", ErrRatesStale, cs.staleDetail(snapshot))
+	}
+	return nil
+}
+
+// exchangeAt converts against a specific snapshot and builds the response
+func (cs *CurrencyService) exchangeAt(ctx context.Context, snapshot *RateSnapshot, from, to string, amount float64) (*ExchangeResponse, error) {
+	convertedAmount, rate, err := cs.convert(ctx, snapshot, from, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	cs.metrics.conversions.Inc(strings.ToUpper(from), strings.ToUpper(to))
+	slog.DebugContext(ctx, "converted currency",
+		"from", from, "to", to, "amount", amount, "rate", rate, "version", snapshot.Version)
+
+	response := &ExchangeResponse{
+		From:            strings.ToUpper(from),
+		To:              strings.ToUpper(to),
+		Amount:          amount,
+		ConvertedAmount: convertedAmount,
+		Rate:            rate,
+		Version:         snapshot.Version,
+		AsOf:            snapshot.AsOf,
+		Source:          snapshot.Source,
+	}
+	if cs.IsStale(snapshot) {
+		response.Warning = cs.staleMessage(snapshot)
+	}
+	return response, nil
+}
+
+// Currencies lists the currency codes of the active snapshot, sorted
+func (cs *CurrencyService) Currencies() []string {
+	snapshot := cs.ActiveSnapshot()
+	codes := make([]string, 0, len(snapshot.Rates))
+	for code := range snapshot.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
 // ExchangeHandler handles currency exchange requests
 func (cs *CurrencyService) ExchangeHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Only GET method is allowed"})
-		return
+	if response, ok := cs.exchangeQuery(w, r); ok {
+		writeResponse(w, r, response)
 	}
+}
 
+// exchangeQuery converts the amount given by the query parameters,
+// writing an error response if that fails
+func (cs *CurrencyService) exchangeQuery(w http.ResponseWriter, r *http.Request) (*ExchangeResponse, bool) {
 	// Parse query parameters
 	from := r.URL.Query().Get("from")
 	to := r.URL.Query().Get("to")
 	amountStr := r.URL.Query().Get("amount")
 
-	if from == "" || to == "" || amountStr == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Missing required parameters: from, to, amount"})
-		return
+	for _, p := range []struct{ name, value string }{{"from", from}, {"to", to}, {"amount", amountStr}} {
+		if p.value == "" {
+			WriteProblem(w, r, missingParameter(p.name))
+			return nil, false
+		}
 	}
 
 	amount, err := strconv.ParseFloat(amountStr, 64)
-	log.Println("Parsed amount:", amount)
-	log.Println("to:", to)
-	log.Println("from:", from)
-
-	if err != nil || amount <= 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid amount parameter"})
-		return
+	if err != nil {
+		WriteProblem(w, r, &RequestError{Code: CodeInvalidAmount, Field: "amount", Message: "Invalid amount parameter"})
+		return nil, false
 	}
 
-	convertedAmount, rate, err := cs.ConvertCurrency(from, to, amount)
+	response, err := cs.Exchange(r.Context(), from, to, amount)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
-		return
+		WriteProblem(w, r, err)
+		return nil, false
 	}
+	return response, true
+}
 
-	response := ExchangeResponse{
-		From:            strings.ToUpper(from),
-		To:              strings.ToUpper(to),
-		Amount:          amount,
-		ConvertedAmount: convertedAmount,
-		Rate:            rate,
+// maxBatchBody bounds the size of a batch request body
+const maxBatchBody = 1 << 20
+
+// ExchangeBatchHandler converts up to MaxBatchSize amounts posted as
+// {"conversions": [...]} against one snapshot. Entries fail on their own.
+func (cs *CurrencyService) ExchangeBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if response, ok := cs.exchangeBody(w, r); ok {
+		writeResponse(w, r, response)
 	}
+}
 
-	json.NewEncoder(w).Encode(response)
+// batchCost makes a batch count as one conversion per entry against the
+// quota. It reads the body ahead of the handler, which decodes it again;
+// batches the handler rejects count as one conversion.
+func batchCost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBody))
+		if err != nil {
+			WriteProblem(w, r, &RequestError{Code: CodeInvalidBody, Message: "Invalid request body: " + err.Error()})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var req struct {
+			Conversions []json.RawMessage `json:"conversions"`
+		}
+		if json.Unmarshal(body, &req) == nil && len(req.Conversions) <= MaxBatchSize {
+			r = r.WithContext(ratelimit.WithCost(r.Context(), len(req.Conversions)))
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
-// HealthHandler handles health check requests
-func (cs *CurrencyService) HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+// exchangeBody converts the batch posted in the request body, writing an
+// error response if the batch as a whole fails
+func (cs *CurrencyService) exchangeBody(w http.ResponseWriter, r *http.Request) (*BatchResponse, bool) {
+	var req BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody)).Decode(&req); err != nil {
+		WriteProblem(w, r, &RequestError{Code: CodeInvalidBody, Message: "Invalid request body: " + err.Error()})
+		return nil, false
+	}
+
+	results, err := cs.ExchangeBatch(r.Context(), req.Conversions)
+	if err != nil {
+		WriteProblem(w, r, err)
+		return nil, false
+	}
+	response := &BatchResponse{Results: make([]BatchResult, len(results))}
+	for i, result := range results {
+		response.Results[i] = BatchResult{request: req.Conversions[i]}
+		if err := result.Err; err != nil {
+			_, code, field := Classify(err)
+			response.Results[i].Error = &BatchError{Code: code, Field: field, Message: err.Error()}
+		} else {
+			response.Results[i].Conversion = result.Response
+		}
+	}
+	return response, true
 }
 
 // RatesHandler returns all available exchange rates
 func (cs *CurrencyService) RatesHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"base":  "USD",
-		"rates": ExchangeRates,
-	})
+	writeResponse(w, r, cs.ratesResponse(cs.ActiveSnapshot()))
+}
+
+func (cs *CurrencyService) ratesResponse(snapshot *RateSnapshot) *RatesResponse {
+	return &RatesResponse{
+		Base:     snapshot.Base,
+		Rates:    snapshot.Rates,
+		Version:  snapshot.Version,
+		AsOf:     snapshot.AsOf,
+		Source:   snapshot.Source,
+		Warning:  cs.StaleWarning(snapshot),
+		Metadata: snapshot.Consensus,
+	}
+}
+
+// SnapshotInfo summarises an installed snapshot for the snapshots listing
+type SnapshotInfo struct {
+	Version     uint64    `json:"version"`
+	Base        string    `json:"base"`
+	Currencies  int       `json:"currencies"`
+	InstalledAt time.Time `json:"installed_at"`
+	Active      bool      `json:"active"`
+}
+
+// SnapshotsResponse lists the installed snapshots and the active version
+type SnapshotsResponse struct {
+	Active    uint64         `json:"active"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+}
+
+// ActivationResponse reports the snapshot a rollback activated
+type ActivationResponse struct {
+	Active uint64    `json:"active"`
+	Base   string    `json:"base"`
+	Rates  RateTable `json:"rates"`
+}
+
+// SnapshotsHandler lists installed rate snapshot versions
+func (cs *CurrencyService) SnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := cs.Snapshots(r.Context())
+	if err != nil {
+		WriteProblem(w, r, err)
+		return
+	}
+
+	active := cs.ActiveSnapshot().Version
+	infos := make([]SnapshotInfo, len(snapshots))
+	for i, s := range snapshots {
+		infos[i] = SnapshotInfo{
+			Version:     s.Version,
+			Base:        s.Base,
+			Currencies:  len(s.Rates),
+			InstalledAt: s.InstalledAt,
+			Active:      s.Version == active,
+		}
+	}
+
+	json.NewEncoder(w).Encode(SnapshotsResponse{Active: active, Snapshots: infos})
+}
+
+// ActivateSnapshotHandler rolls the active rate table back to the
+// snapshot version given in the {id} path segment
+func (cs *CurrencyService) ActivateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		WriteProblem(w, r, &RequestError{Code: CodeInvalidParameter, Field: "id", Message: "Invalid snapshot id"})
+		return
+	}
+
+	snapshot, err := cs.ActivateSnapshot(r.Context(), version)
+	if err != nil {
+		WriteProblem(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ActivationResponse{Active: snapshot.Version, Base: snapshot.Base, Rates: snapshot.Rates})
 }
@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"currency_go_microservice/internal/logging"
+	"currency_go_microservice/internal/router"
 )
 
 // Unit Tests for ConvertCurrency function
@@ -102,6 +105,8 @@ func TestConvertCurrency(t *testing.T) {
 // Unit Tests for HTTP Handlers
 func TestExchangeHandler(t *testing.T) {
 	cs := NewCurrencyService()
+	rt := router.New(WriteError)
+	cs.RegisterRoutes(rt, RouteOptions{})
 
 	tests := []struct {
 		name           string
@@ -189,8 +194,7 @@ func TestExchangeHandler(t *testing.T) {
 			}
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(cs.ExchangeHandler)
-			handler.ServeHTTP(rr, req)
+			rt.ServeHTTP(rr, req)
 
 			if status := rr.Code; status != tt.expectedStatus {
 				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, status)
@@ -244,7 +248,7 @@ func TestHealthHandler(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
 	}
 
-	var response map[string]string
+	var response map[string]interface{}
 	err = json.Unmarshal(rr.Body.Bytes(), &response)
 	if err != nil {
 		t.Errorf("Could not parse JSON response: %v", err)
@@ -314,3 +318,20 @@ func BenchmarkExchangeHandler(b *testing.B) {
 		handler.ServeHTTP(rr, req)
 	}
 }
+
+func TestErrorResponseIncludesRequestID(t *testing.T) {
+	cs := NewCurrencyService()
+
+	req := httptest.NewRequest("GET", "/exchange?from=XYZ&to=EUR&amount=100", nil)
+	req = req.WithContext(logging.WithRequestID(req.Context(), "req-42"))
+	rr := httptest.NewRecorder()
+	cs.ExchangeHandler(rr, req)
+
+	var errorResp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if errorResp.RequestID != "req-42" {
+		t.Errorf("Expected request_id 'req-42', got '%s'", errorResp.RequestID)
+	}
+}
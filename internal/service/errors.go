@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"currency_go_microservice/internal/logging"
+)
+
+// Error codes identify what went wrong in ErrorResponse.Code. Unlike the
+// messages they are stable, so clients can branch on them.
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeInvalidBody         = "INVALID_BODY"
+	CodeMissingParameter    = "MISSING_PARAMETER"
+	CodeInvalidParameter    = "INVALID_PARAMETER"
+	CodeInvalidAmount       = "INVALID_AMOUNT"
+	CodeUnsupportedCurrency = "UNSUPPORTED_CURRENCY"
+	CodeBatchTooLarge       = "BATCH_TOO_LARGE"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeSnapshotNotFound    = "SNAPSHOT_NOT_FOUND"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable       = "NOT_ACCEPTABLE"
+	CodeUpgradeRequired     = "UPGRADE_REQUIRED"
+	CodeRateLimited         = "RATE_LIMITED"
+	CodeInternal            = "INTERNAL_ERROR"
+	CodeRatesStale          = "RATES_STALE"
+	CodeUnavailable         = "SERVICE_UNAVAILABLE"
+)
+
+// errorCodes lists every code, in the order the OpenAPI document gives them
+var errorCodes = []string{
+	CodeInvalidRequest, CodeInvalidBody, CodeMissingParameter, CodeInvalidParameter,
+	CodeInvalidAmount, CodeUnsupportedCurrency, CodeBatchTooLarge, CodeUnauthorized,
+	CodeForbidden, CodeNotFound, CodeSnapshotNotFound, CodeMethodNotAllowed,
+	CodeNotAcceptable, CodeUpgradeRequired, CodeRateLimited, CodeInternal,
+	CodeRatesStale, CodeUnavailable,
+}
+
+// statusCodes are the codes of errors known only by their HTTP status, as
+// reported by the router and the auth and rate limit middleware
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          CodeInvalidRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+	http.StatusNotAcceptable:       CodeNotAcceptable,
+	http.StatusUpgradeRequired:     CodeUpgradeRequired,
+	http.StatusTooManyRequests:     CodeRateLimited,
+	http.StatusInternalServerError: CodeInternal,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+}
+
+// CodeForStatus returns the code of a status, deriving one from the
+// status text for statuses without their own
+func CodeForStatus(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+var (
+	// ErrInvalidAmount is returned for amounts that are not positive
+	ErrInvalidAmount = errors.New("amount must be a positive number")
+	// ErrRatesStale is returned for conversions refused under StaleReject
+	ErrRatesStale = errors.New("exchange rates are stale")
+)
+
+// UnsupportedCurrencyError reports a currency missing from the rate table.
+// Field names the parameter that carried it.
+type UnsupportedCurrencyError struct {
+	Currency string
+	Field    string
+}
+
+func (e *UnsupportedCurrencyError) Error() string {
+	return fmt.Sprintf("currency %s not supported", e.Currency)
+}
+
+// RequestError is a malformed request, reported as 400 Bad Request
+type RequestError struct {
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// missingParameter reports a required parameter that was not sent
+func missingParameter(field string) *RequestError {
+	return &RequestError{Code: CodeMissingParameter, Field: field, Message: "Missing required parameter: " + field}
+}
+
+// Classify returns the HTTP status, code and offending field for an error
+// returned by the service. Errors it does not know are internal errors.
+func Classify(err error) (status int, code, field string) {
+	var unsupported *UnsupportedCurrencyError
+	var request *RequestError
+	switch {
+	case errors.As(err, &request):
+		return http.StatusBadRequest, request.Code, request.Field
+	case errors.As(err, &unsupported):
+		return http.StatusBadRequest, CodeUnsupportedCurrency, unsupported.Field
+	case errors.Is(err, ErrInvalidAmount):
+		return http.StatusBadRequest, CodeInvalidAmount, "amount"
+	case errors.Is(err, ErrRatesStale):
+		return http.StatusServiceUnavailable, CodeRatesStale, ""
+	case errors.Is(err, ErrSnapshotNotFound):
+		return http.StatusNotFound, CodeSnapshotNotFound, "id"
+	}
+	return http.StatusInternalServerError, CodeInternal, ""
+}
+
+// problemMediaType is the RFC 7807 media type, sent to clients that ask
+// for it
+const problemMediaType = "application/problem+json"
+
+// ErrorResponse is the body of every error response: an RFC 7807 problem
+// document extended with a stable code, the offending field and the
+// request ID. Error repeats Detail for clients of the original format.
+type ErrorResponse struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	Field     string `json:"field,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Error     string `json:"error"`
+}
+
+// WriteError sends an ErrorResponse with the code for status. It has the
+// router.ErrorWriter signature so router, auth and rate limit errors share
+// the format.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeProblem(w, r, status, CodeForStatus(status), "", message)
+}
+
+// WriteProblem sends an ErrorResponse for an error returned by the
+// service, with the status, code and field given by Classify
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, field := Classify(err)
+	writeProblem(w, r, status, code, field, err.Error())
+}
+
+// writeProblem sends the response as application/problem+json when the
+// client accepts it and as application/json otherwise
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, field, detail string) {
+	contentType := "application/json"
+	for _, mr := range parseAccept(strings.Join(r.Header.Values("Accept"), ",")) {
+		if mr.typ+"/"+mr.subtype == problemMediaType && mr.q > 0 {
+			contentType = problemMediaType
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		Field:     field,
+		RequestID: logging.RequestID(r.Context()),
+		Error:     detail,
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currency_go_microservice/internal/logging"
+	"currency_go_microservice/internal/router"
+)
+
+func TestProblemResponses(t *testing.T) {
+	rt := router.New(WriteError)
+	rt.Use(logging.RequestIDs)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{SnapshotAdmin: true})
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedField  string
+	}{
+		{"Missing from", "GET", "/exchange?to=EUR&amount=1", "", 400, CodeMissingParameter, "from"},
+		{"Missing amount", "GET", "/exchange?from=USD&to=EUR", "", 400, CodeMissingParameter, "amount"},
+		{"Unparseable amount", "GET", "/exchange?from=USD&to=EUR&amount=ten", "", 400, CodeInvalidAmount, "amount"},
+		{"Negative amount", "GET", "/exchange?from=USD&to=EUR&amount=-1", "", 400, CodeInvalidAmount, "amount"},
+		{"Unsupported source", "GET", "/exchange?from=XYZ&to=EUR&amount=1", "", 400, CodeUnsupportedCurrency, "from"},
+		{"Unsupported target", "GET", "/exchange?from=USD&to=XYZ&amount=1", "", 400, CodeUnsupportedCurrency, "to"},
+		{"Unsupported stream symbol", "GET", "/rates/stream?symbols=XYZ", "", 400, CodeUnsupportedCurrency, "symbols"},
+		{"Malformed batch", "POST", "/exchange/batch", "[", 400, CodeInvalidBody, ""},
+		{"Empty batch", "POST", "/exchange/batch", `{}`, 400, CodeMissingParameter, "conversions"},
+		{"Invalid snapshot id", "POST", "/snapshots/abc/activate", "", 400, CodeInvalidParameter, "id"},
+		{"Unknown snapshot", "POST", "/snapshots/99/activate", "", 404, CodeSnapshotNotFound, "id"},
+		{"Unknown path", "GET", "/nope", "", 404, CodeNotFound, ""},
+		{"Wrong method", "DELETE", "/rates", "", 405, CodeMethodNotAllowed, ""},
+		{"Not acceptable", "GET", "/rates?format=yaml", "", 406, CodeNotAcceptable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			var resp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Invalid error response %q: %v", rr.Body.String(), err)
+			}
+			if rr.Code != tt.expectedStatus || resp.Status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d with body status %d", tt.expectedStatus, rr.Code, resp.Status)
+			}
+			if resp.Code != tt.expectedCode || resp.Field != tt.expectedField {
+				t.Errorf("Expected code %s on field %q, got %s on %q", tt.expectedCode, tt.expectedField, resp.Code, resp.Field)
+			}
+			if resp.Type != "about:blank" || resp.Title != http.StatusText(tt.expectedStatus) || resp.Instance != req.URL.Path {
+				t.Errorf("Unexpected problem members: %+v", resp)
+			}
+			if resp.Detail == "" || resp.Error != resp.Detail || resp.RequestID != rr.Header().Get(logging.RequestIDHeader) {
+				t.Errorf("Expected detail, error and request ID to be set: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestProblemMediaType(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", "application/json"},
+		{"application/json", "application/json"},
+		{"application/problem+json", "application/problem+json"},
+		{"application/json, application/problem+json;q=0.5", "application/problem+json"},
+		{"application/problem+json;q=0", "application/json"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/exchange", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rr := httptest.NewRecorder()
+		WriteProblem(rr, req, ErrInvalidAmount)
+		if ct := rr.Header().Get("Content-Type"); ct != tt.expected {
+			t.Errorf("Accept %q: expected %s, got %s", tt.accept, tt.expected, ct)
+		}
+	}
+}
+
+func TestConvertCurrencyTypedErrors(t *testing.T) {
+	cs := NewCurrencyService()
+
+	_, _, err := cs.ConvertCurrency("USD", "XYZ", 10)
+	var unsupported *UnsupportedCurrencyError
+	if !errors.As(err, &unsupported) || unsupported.Currency != "XYZ" || unsupported.Field != "to" {
+		t.Errorf("Expected an unsupported target currency, got %v", err)
+	}
+
+	_, _, err = cs.ConvertCurrency("USD", "EUR", 0)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
+	// Wrapped errors keep their classification
+	status, code, field := Classify(fmt.Errorf("converting: %w", err))
+	if status != http.StatusBadRequest || code != CodeInvalidAmount || field != "amount" {
+		t.Errorf("Expected 400 %s on amount, got %d %s on %q", CodeInvalidAmount, status, code, field)
+	}
+	if status, code, _ := Classify(errors.New("disk full")); status != http.StatusInternalServerError || code != CodeInternal {
+		t.Errorf("Expected unknown errors to be internal, got %d %s", status, code)
+	}
+}
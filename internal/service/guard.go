@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// AnomalyGuard decides whether a new rate snapshot looks plausible
+// compared to the one currently active
+type AnomalyGuard struct {
+	// MaxChangePercent is the largest allowed move for any currency
+	// between two snapshots; zero disables the check
+	MaxChangePercent float64
+	// MaxChangePercentByCurrency overrides MaxChangePercent per currency
+	MaxChangePercentByCurrency map[string]float64
+	// MinRate and MaxRate are absolute sanity bounds for every rate
+	MinRate float64
+	MaxRate float64
+	// AllowMissing accepts snapshots that drop currencies the previous
+	// snapshot had
+	AllowMissing bool
+}
+
+// DefaultAnomalyGuard returns the guard used when none is configured
+func DefaultAnomalyGuard() *AnomalyGuard {
+	return &AnomalyGuard{
+		MaxChangePercent: 20,
+		MinRate:          1e-6,
+		MaxRate:          1e7,
+	}
+}
+
+// Violation describes a single reason a snapshot was rejected
+type Violation struct {
+	Currency string  `json:"currency"`
+	Reason   string  `json:"reason"`
+	Previous float64 `json:"previous,omitempty"`
+	Proposed float64 `json:"proposed,omitempty"`
+}
+
+// AnomalyError is returned when a snapshot is quarantined
+type AnomalyError struct {
+	Violations []Violation
+}
+
+func (e *AnomalyError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = fmt.Sprintf("%s: %s", v.Currency, v.Reason)
+	}
+	return "suspicious rate snapshot: " + strings.Join(parts, "; ")
+}
+
+// Check compares next against prev and returns every violation found.
+// prev may be nil for the first snapshot, in which case only the sanity
+// bounds are applied.
+func (g *AnomalyGuard) Check(prev, next *RateSnapshot) []Violation {
+	if g == nil {
+		return nil
+	}
+
+	var violations []Violation
+	codes := make([]string, 0, len(next.Rates))
+	for code := range next.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		rate := next.Rates[code]
+		switch {
+		case math.IsNaN(rate) || math.IsInf(rate, 0) || rate <= 0:
+			violations = append(violations, Violation{Currency: code, Reason: "rate must be a positive number", Proposed: rate})
+			continue
+		case g.MinRate > 0 && rate < g.MinRate:
+			violations = append(violations, Violation{Currency: code, Reason: fmt.Sprintf("rate below minimum %g", g.MinRate), Proposed: rate})
+			continue
+		case g.MaxRate > 0 && rate > g.MaxRate:
+			violations = append(violations, Violation{Currency: code, Reason: fmt.Sprintf("rate above maximum %g", g.MaxRate), Proposed: rate})
+			continue
+		}
+
+		if prev == nil {
+			continue
+		}
+		old, ok := prev.Rates[code]
+		if !ok || old <= 0 {
+			continue
+		}
+		limit := g.MaxChangePercent
+		if override, ok := g.MaxChangePercentByCurrency[code]; ok {
+			limit = override
+		}
+		if limit <= 0 {
+			continue
+		}
+		if change := math.Abs(rate-old) / old * 100; change > limit {
+			violations = append(violations, Violation{
+				Currency: code,
+				Reason:   fmt.Sprintf("changed %.2f%%, limit is %.2f%%", change, limit),
+				Previous: old,
+				Proposed: rate,
+			})
+		}
+	}
+
+	if prev != nil && !g.AllowMissing {
+		missing := make([]string, 0)
+		for code := range prev.Rates {
+			if _, ok := next.Rates[code]; !ok {
+				missing = append(missing, code)
+			}
+		}
+		sort.Strings(missing)
+		for _, code := range missing {
+			violations = append(violations, Violation{Currency: code, Reason: "currency missing from snapshot", Previous: prev.Rates[code]})
+		}
+	}
+
+	return violations
+}
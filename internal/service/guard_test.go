@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInstallSnapshotAnomalyGuard(t *testing.T) {
+	tests := []struct {
+		name        string
+		rates       map[string]float64
+		expectError bool
+	}{
+		{
+			name:        "Small move accepted",
+			rates:       withRate("EUR", 0.86),
+			expectError: false,
+		},
+		{
+			name:        "Decimal slip rejected",
+			rates:       withRate("EUR", 85),
+			expectError: true,
+		},
+		{
+			name:        "Negative rate rejected",
+			rates:       withRate("GBP", -0.73),
+			expectError: true,
+		},
+		{
+			name:        "Rate above sanity bound rejected",
+			rates:       withRate("JPY", 1e9),
+			expectError: true,
+		},
+		{
+			name:        "Missing currency rejected",
+			rates:       withoutRate("CHF"),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alerts int
+			cs := NewCurrencyService(WithQuarantineAlert(func(QuarantinedSnapshot) { alerts++ }))
+			now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+			cs.now = func() time.Time { return now }
+
+			err := cs.InstallSnapshot(NewRateSnapshot("USD", tt.rates))
+
+			if !tt.expectError {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got := cs.ActiveSnapshot().Rates["EUR"]; got != tt.rates["EUR"] {
+					t.Errorf("Expected new snapshot to be active, EUR rate is %v", got)
+				}
+				return
+			}
+
+			var anomaly *AnomalyError
+			if !errors.As(err, &anomaly) {
+				t.Fatalf("Expected *AnomalyError, got %v", err)
+			}
+			if cs.ActiveSnapshot().Rates["EUR"] != ExchangeRates["EUR"] {
+				t.Errorf("Expected previous snapshot to stay active")
+			}
+			if cs.QuarantineCount() != 1 || len(cs.Quarantined()) != 1 {
+				t.Fatalf("Expected snapshot to be quarantined, count %d", cs.QuarantineCount())
+			}
+			if at := cs.Quarantined()[0].At; !at.Equal(now) {
+				t.Errorf("Expected quarantine time %v, got %v", now, at)
+			}
+			if alerts != 1 {
+				t.Errorf("Expected 1 alert, got %d", alerts)
+			}
+		})
+	}
+}
+
+func TestInstallSnapshotPerCurrencyLimit(t *testing.T) {
+	guard := DefaultAnomalyGuard()
+	guard.MaxChangePercentByCurrency = map[string]float64{"BRL": 50}
+	cs := NewCurrencyService(WithAnomalyGuard(guard))
+
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("BRL", 7.0))); err != nil {
+		t.Errorf("Expected BRL override to allow a 35%% move, got %v", err)
+	}
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("EUR", 1.2))); err == nil {
+		t.Errorf("Expected default limit to still apply to EUR")
+	}
+}
+
+func withRate(code string, rate float64) map[string]float64 {
+	rates := make(map[string]float64, len(ExchangeRates))
+	for c, r := range ExchangeRates {
+		rates[c] = r
+	}
+	rates[code] = rate
+	return rates
+}
+
+func withoutRate(code string) map[string]float64 {
+	rates := withRate(code, 0)
+	delete(rates, code)
+	return rates
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"currency_go_microservice/internal/provider"
+)
+
+// storageCheckTimeout bounds how long the readiness probe waits for storage
+const storageCheckTimeout = 2 * time.Second
+
+// CheckStatus is the outcome of a single health check
+type CheckStatus string
+
+const (
+	// CheckPass means the dependency is fine
+	CheckPass CheckStatus = "pass"
+	// CheckWarn means the service can serve traffic in a degraded way
+	CheckWarn CheckStatus = "warn"
+	// CheckFail means the service cannot serve traffic
+	CheckFail CheckStatus = "fail"
+)
+
+// CheckResult is the outcome and explanation of a health check
+type CheckResult struct {
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message,omitempty"`
+}
+
+// ProbeResponse is returned by /livez and /readyz
+type ProbeResponse struct {
+	Status CheckStatus            `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Pinger is implemented by snapshot stores that can cheaply check they are
+// reachable. Stores without it are checked by listing snapshots.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ReadinessChecks runs every dependency check used by /readyz
+func (cs *CurrencyService) ReadinessChecks(ctx context.Context) map[string]CheckResult {
+	return map[string]CheckResult{
+		"rates":    cs.checkRates(),
+		"storage":  cs.checkStorage(ctx),
+		"provider": cs.checkProvider(),
+	}
+}
+
+func (cs *CurrencyService) checkRates() CheckResult {
+	snapshot := cs.ActiveSnapshot()
+	if snapshot == nil || len(snapshot.Rates) == 0 {
+		return CheckResult{Status: CheckFail, Message: "no rate snapshot loaded"}
+	}
+	return CheckResult{Status: CheckPass, Message: fmt.Sprintf("snapshot %d with %d currencies", snapshot.Version, len(snapshot.Rates))}
+}
+
+func (cs *CurrencyService) checkStorage(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, storageCheckTimeout)
+	defer cancel()
+
+	var err error
+	if pinger, ok := cs.store.(Pinger); ok {
+		err = pinger.Ping(ctx)
+	} else {
+		_, err = cs.listSnapshots(ctx)
+	}
+	if err != nil {
+		return CheckResult{Status: CheckFail, Message: "snapshot store unreachable: " + err.Error()}
+	}
+	return CheckResult{Status: CheckPass}
+}
+
+func (cs *CurrencyService) checkProvider() CheckResult {
+	snapshot := cs.ActiveSnapshot()
+	if snapshot != nil && cs.IsStale(snapshot) {
+		status := CheckWarn
+		if cs.stalePolicy == StaleReject {
+			status = CheckFail
+		}
+		return CheckResult{Status: status, Message: cs.staleMessage(snapshot)}
+	}
+	if status, ok := cs.ProviderStatus(); ok && status.ActiveSource == provider.CacheSource {
+		return CheckResult{Status: CheckWarn, Message: "all rate providers failing, serving cached rates"}
+	}
+	return CheckResult{Status: CheckPass}
+}
+
+// overallStatus returns the worst status among checks
+func overallStatus(checks map[string]CheckResult) CheckStatus {
+	status := CheckPass
+	for _, c := range checks {
+		switch {
+		case c.Status == CheckFail:
+			return CheckFail
+		case c.Status == CheckWarn:
+			status = CheckWarn
+		}
+	}
+	return status
+}
+
+// LivenessHandler reports that the process is alive and serving HTTP
+func (cs *CurrencyService) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ProbeResponse{Status: CheckPass})
+}
+
+// ReadinessHandler reports whether the service can serve conversions.
+// It returns 503 when any check fails so traffic is routed elsewhere.
+func (cs *CurrencyService) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	checks := cs.ReadinessChecks(r.Context())
+	response := ProbeResponse{Status: overallStatus(checks), Checks: checks}
+	if response.Status == CheckFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// HealthResponse is returned by /health. Status is "healthy", "degraded"
+// or "unhealthy"; the provider fields are only set when the rate provider
+// reports its status.
+type HealthResponse struct {
+	Status             string                   `json:"status"`
+	Checks             map[string]CheckResult   `json:"checks"`
+	SnapshotAgeSeconds int64                    `json:"snapshot_age_seconds"`
+	Warning            string                   `json:"warning,omitempty"`
+	RatesSource        string                   `json:"rates_source,omitempty"`
+	Providers          []provider.BreakerStatus `json:"providers,omitempty"`
+}
+
+// HealthHandler handles health check requests. It aggregates the liveness
+// and readiness checks into "healthy", "degraded" or "unhealthy"; only
+// unhealthy responds with 503.
+func (cs *CurrencyService) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	checks := cs.ReadinessChecks(r.Context())
+	checks["live"] = CheckResult{Status: CheckPass}
+	response := HealthResponse{Checks: checks}
+
+	code := http.StatusOK
+	switch overallStatus(checks) {
+	case CheckPass:
+		response.Status = "healthy"
+	case CheckWarn:
+		response.Status = "degraded"
+	default:
+		response.Status = "unhealthy"
+		code = http.StatusServiceUnavailable
+	}
+
+	snapshot := cs.ActiveSnapshot()
+	response.SnapshotAgeSeconds = int64(cs.SnapshotAge(snapshot).Seconds())
+	if cs.IsStale(snapshot) {
+		response.Warning = cs.staleMessage(snapshot)
+	}
+	if status, ok := cs.ProviderStatus(); ok {
+		response.RatesSource = status.ActiveSource
+		response.Providers = status.Breakers
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
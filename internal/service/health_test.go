@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// unreachableStore is a SnapshotStore whose backend is down
+type unreachableStore struct{ *MemorySnapshotStore }
+
+func (s unreachableStore) List(ctx context.Context) ([]*RateSnapshot, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestProbeHandlers(t *testing.T) {
+	tests := []struct {
+		name            string
+		service         func() *CurrencyService
+		handler         func(cs *CurrencyService) http.HandlerFunc
+		expectedStatus  int
+		expectedOverall CheckStatus
+		failingCheck    string
+	}{
+		{
+			name: "Liveness always passes",
+			service: func() *CurrencyService {
+				return NewCurrencyService(WithSnapshotStore(unreachableStore{NewMemorySnapshotStore(0)}))
+			},
+			handler:         func(cs *CurrencyService) http.HandlerFunc { return cs.LivenessHandler },
+			expectedStatus:  http.StatusOK,
+			expectedOverall: CheckPass,
+		},
+		{
+			name:            "Ready with builtin rates",
+			service:         func() *CurrencyService { return NewCurrencyService() },
+			handler:         func(cs *CurrencyService) http.HandlerFunc { return cs.ReadinessHandler },
+			expectedStatus:  http.StatusOK,
+			expectedOverall: CheckPass,
+		},
+		{
+			name: "Not ready when storage is unreachable",
+			service: func() *CurrencyService {
+				return NewCurrencyService(WithSnapshotStore(unreachableStore{NewMemorySnapshotStore(0)}))
+			},
+			handler:         func(cs *CurrencyService) http.HandlerFunc { return cs.ReadinessHandler },
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedOverall: CheckFail,
+			failingCheck:    "storage",
+		},
+		{
+			name: "Not ready when stale rates are rejected",
+			service: func() *CurrencyService {
+				cs := NewCurrencyService(WithMaxAge(time.Minute, StaleReject))
+				cs.now = func() time.Time { return time.Now().Add(time.Hour) }
+				return cs
+			},
+			handler:         func(cs *CurrencyService) http.HandlerFunc { return cs.ReadinessHandler },
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedOverall: CheckFail,
+			failingCheck:    "provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := tt.service()
+			rr := httptest.NewRecorder()
+			tt.handler(cs)(rr, httptest.NewRequest("GET", "/", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			var response ProbeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse JSON response: %v", err)
+			}
+			if response.Status != tt.expectedOverall {
+				t.Errorf("Expected overall status %s, got %s", tt.expectedOverall, response.Status)
+			}
+			if tt.failingCheck != "" && response.Checks[tt.failingCheck].Status != CheckFail {
+				t.Errorf("Expected %s check to fail, got %+v", tt.failingCheck, response.Checks)
+			}
+		})
+	}
+}
+
+func TestHealthHandlerAggregatesChecks(t *testing.T) {
+	cs := NewCurrencyService(WithSnapshotStore(unreachableStore{NewMemorySnapshotStore(0)}))
+
+	rr := httptest.NewRecorder()
+	cs.HealthHandler(rr, httptest.NewRequest("GET", "/health", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+
+	var response struct {
+		Status string                 `json:"status"`
+		Checks map[string]CheckResult `json:"checks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if response.Status != "unhealthy" {
+		t.Errorf("Expected status 'unhealthy', got '%s'", response.Status)
+	}
+	for _, name := range []string{"live", "rates", "storage", "provider"} {
+		if _, ok := response.Checks[name]; !ok {
+			t.Errorf("Expected %s check in health response", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"currency_go_microservice/internal/metrics"
+)
+
+// serviceMetrics are the metrics reported by the currency service
+type serviceMetrics struct {
+	conversions *metrics.CounterVec
+	refreshes   *metrics.CounterVec
+	quarantined *metrics.CounterVec
+	// slowConsumers counts stream clients dropped for not keeping up
+	slowConsumers *metrics.CounterVec
+}
+
+// WithMetrics registers the service metrics on reg so they are exposed by
+// its handler. Without it metrics are kept in a private registry.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(cs *CurrencyService) { cs.registry = reg }
+}
+
+func newServiceMetrics(reg *metrics.Registry, cs *CurrencyService) *serviceMetrics {
+	m := &serviceMetrics{
+		conversions: reg.NewCounterVec("currency_conversions_total",
+			"Successful conversions by currency pair.", "from", "to"),
+		refreshes: reg.NewCounterVec("rate_refresh_total",
+			"Rate refresh attempts by result: success, failure or cached.", "result"),
+		quarantined: reg.NewCounterVec("rate_snapshots_quarantined_total",
+			"Rate snapshots rejected by the anomaly guard."),
+		slowConsumers: reg.NewCounterVec("rate_stream_slow_consumers_total",
+			"Rate stream clients disconnected for not reading fast enough, by transport.", "transport"),
+	}
+
+	reg.NewGaugeFunc("rate_snapshot_age_seconds", "Age of the active rate snapshot's as_of time.", nil,
+		func(emit func(float64, ...string)) {
+			emit(cs.SnapshotAge(cs.ActiveSnapshot()).Seconds())
+		})
+	reg.NewGaugeFunc("rate_snapshot_version", "Version of the active rate snapshot.", nil,
+		func(emit func(float64, ...string)) {
+			emit(float64(cs.ActiveSnapshot().Version))
+		})
+	reg.NewGaugeFunc("exchange_rate", "Current rate per currency against the snapshot base.", []string{"base", "currency"},
+		func(emit func(float64, ...string)) {
+			snapshot := cs.ActiveSnapshot()
+			for code, rate := range snapshot.Rates {
+				emit(rate, snapshot.Base, code)
+			}
+		})
+	reg.NewGaugeFunc("rate_stream_clients", "Connected rate stream clients by transport.", []string{"transport"},
+		func(emit func(float64, ...string)) {
+			for _, transport := range []string{transportSSE, transportWebSocket} {
+				emit(float64(cs.streams.count(transport)), transport)
+			}
+		})
+	return m
+}
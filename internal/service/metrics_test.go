@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currency_go_microservice/internal/metrics"
+	"currency_go_microservice/internal/provider"
+)
+
+func TestServiceMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	cs := NewCurrencyService(WithMetrics(reg), WithRateProvider(provider.NewStaticProvider("feed", "USD", ExchangeRates)))
+
+	cs.ExchangeHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/exchange?from=usd&to=EUR&amount=10", nil))
+	cs.ExchangeHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/exchange?from=USD&to=XYZ&amount=10", nil))
+	if err := cs.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("EUR", 85))); err == nil {
+		t.Fatalf("Expected anomalous snapshot to be quarantined")
+	}
+
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	out := rr.Body.String()
+
+	expected := []string{
+		`currency_conversions_total{from="USD",to="EUR"} 1`,
+		`rate_refresh_total{result="success"} 1`,
+		"rate_snapshots_quarantined_total 1",
+		"rate_snapshot_version 2",
+		`exchange_rate{base="USD",currency="EUR"} 0.85`,
+		"# TYPE rate_snapshot_age_seconds gauge",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in metrics output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `to="XYZ"`) {
+		t.Errorf("Expected failed conversions not to be counted:\n%s", out)
+	}
+}
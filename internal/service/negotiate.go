@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"currency_go_microservice/internal/msgpack"
+	"currency_go_microservice/internal/router"
+)
+
+// format is a response encoding offered through content negotiation
+type format struct {
+	// name is the value of the format query parameter
+	name string
+	// mediaType is sent as the Content-Type
+	mediaType string
+	// aliases are other media types clients may ask for it by
+	aliases []string
+}
+
+// formats lists the response encodings in order of preference, JSON first
+var formats = []format{
+	{name: "json", mediaType: "application/json"},
+	{name: "xml", mediaType: "application/xml", aliases: []string{"text/xml"}},
+	{name: "csv", mediaType: "text/csv"},
+	{name: "msgpack", mediaType: "application/msgpack", aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}},
+}
+
+func (f format) matches(mediaType string) bool {
+	if mediaType == f.mediaType {
+		return true
+	}
+	for _, alias := range f.aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// offer returns the named formats, keeping their order of preference
+func offer(names ...string) []format {
+	var offered []format
+	for _, f := range formats {
+		for _, name := range names {
+			if f.name == name {
+				offered = append(offered, f)
+			}
+		}
+	}
+	return offered
+}
+
+// negotiateFormat picks the response format for r among offered, the
+// first of which is the default. The format query parameter wins over the
+// Accept header. ok is false when nothing acceptable is offered.
+func negotiateFormat(r *http.Request, offered []format) (f format, ok bool) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, f := range offered {
+			if strings.EqualFold(name, f.name) {
+				return f, true
+			}
+		}
+		return format{}, false
+	}
+
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return offered[0], true
+	}
+	ranges := parseAccept(strings.Join(accept, ","))
+	best, bestQ := format{}, 0.0
+	for _, f := range offered {
+		if q := acceptQuality(ranges, f); q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaRange is one entry of an Accept header
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, mediaRange{typ, subtype, q})
+	}
+	return ranges
+}
+
+// acceptQuality is the q of the most specific range matching f, so
+// "*/*, text/csv;q=0" refuses CSV
+func acceptQuality(ranges []mediaRange, f format) float64 {
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		var s int
+		switch {
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		case mr.subtype == "*" && strings.HasPrefix(f.mediaType, mr.typ+"/"):
+			s = 1
+		case f.matches(mr.typ + "/" + mr.subtype):
+			s = 2
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
+
+type formatKey struct{}
+
+// negotiate chooses among all formats, as the v1 routes offer
+var negotiate = negotiator(formats)
+
+// negotiator returns middleware that chooses the response format before
+// the handler runs, replying 406 Not Acceptable when none of offered is
+// acceptable. Handlers write their result with writeResponse.
+func negotiator(offered []format) router.Middleware {
+	names := make([]string, len(offered))
+	for i, f := range offered {
+		names[i] = f.mediaType
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			f, ok := negotiateFormat(r, offered)
+			if !ok {
+				WriteError(w, r, http.StatusNotAcceptable,
+					"Unsupported response format, expected one of "+strings.Join(names, ", "))
+				return
+			}
+			w.Header().Set("Content-Type", f.mediaType)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), formatKey{}, f)))
+		})
+	}
+}
+
+// csvMarshaler is implemented by responses that can be written as CSV.
+// The first record is the header.
+type csvMarshaler interface {
+	csvRecords() [][]string
+}
+
+// writeResponse writes v in the format chosen by negotiate, or as JSON on
+// routes without it. XML uses v's xml tags and CSV requires v to provide
+// its records.
+func writeResponse(w http.ResponseWriter, r *http.Request, v any) {
+	f, ok := r.Context().Value(formatKey{}).(format)
+	if !ok {
+		f = formats[0]
+	}
+
+	var err error
+	switch f.name {
+	case "xml":
+		if _, err = w.Write([]byte(xml.Header)); err == nil {
+			err = xml.NewEncoder(w).Encode(v)
+		}
+	case "csv":
+		records, ok := v.(csvMarshaler)
+		if !ok {
+			err = fmt.Errorf("%T has no CSV form", v)
+			break
+		}
+		err = csv.NewWriter(w).WriteAll(records.csvRecords())
+	case "msgpack":
+		var b []byte
+		if b, err = msgpack.Marshal(v); err == nil {
+			_, err = w.Write(b)
+		}
+	default:
+		err = json.NewEncoder(w).Encode(v)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to write response", "format", f.name, "error", err)
+	}
+}
+
+// formatFloat writes a float without an exponent
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
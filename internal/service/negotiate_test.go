@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/router"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		accept   string
+		expected string
+	}{
+		{"No Accept header", "/rates", "", "json"},
+		{"Any type", "/rates", "*/*", "json"},
+		{"Exact type", "/rates", "text/csv", "csv"},
+		{"Alias", "/rates", "text/xml", "xml"},
+		{"MessagePack alias", "/rates", "application/x-msgpack", "msgpack"},
+		{"Highest quality wins", "/rates", "application/json;q=0.5, application/xml", "xml"},
+		{"Browser style header", "/rates", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "xml"},
+		{"Type wildcard", "/rates", "text/*", "csv"},
+		{"Specific refusal beats wildcard", "/rates", "*/*, application/json;q=0", "xml"},
+		{"Unsupported type", "/rates", "text/html", ""},
+		{"Everything refused", "/rates", "*/*;q=0", ""},
+		{"Format parameter wins", "/rates?format=CSV", "application/json", "csv"},
+		{"Unknown format parameter", "/rates?format=yaml", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			f, ok := negotiateFormat(req, formats)
+			if ok != (tt.expected != "") || f.name != tt.expected {
+				t.Errorf("Expected format %q, got %q (ok %v)", tt.expected, f.name, ok)
+			}
+		})
+	}
+}
+
+func TestNegotiatedResponses(t *testing.T) {
+	cs := NewCurrencyService(WithAnomalyGuard(nil))
+	snapshot := NewRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.85, "GBP": 0.73})
+	snapshot.AsOf = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot.Source = "builtin"
+	if err := cs.InstallSnapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	rt := router.New(WriteError)
+	cs.RegisterRoutes(rt, RouteOptions{})
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		accept       string
+		expectedType string
+		check        func(t *testing.T, body []byte)
+	}{
+		{"Rates as XML", "GET", "/rates", "", "application/xml", "application/xml", func(t *testing.T, body []byte) {
+			expected := `<exchange_rates><base>USD</base><rates><rate currency="EUR">0.85</rate><rate currency="GBP">0.73</rate>` +
+				`<rate currency="USD">1</rate></rates><version>2</version><as_of>2025-01-01T00:00:00Z</as_of><source>builtin</source></exchange_rates>`
+			if !bytes.HasPrefix(body, []byte(xml.Header)) || !strings.Contains(string(body), expected) {
+				t.Errorf("Unexpected XML: %s", body)
+			}
+		}},
+		{"Rates as CSV", "GET", "/rates?format=csv", "", "", "text/csv", func(t *testing.T, body []byte) {
+			expectCSV(t, body, [][]string{
+				{"base", "currency", "rate", "version", "as_of", "source", "warning"},
+				{"USD", "EUR", "0.85", "2", "2025-01-01T00:00:00Z", "builtin", ""},
+				{"USD", "GBP", "0.73", "2", "2025-01-01T00:00:00Z", "builtin", ""},
+				{"USD", "USD", "1", "2", "2025-01-01T00:00:00Z", "builtin", ""},
+			})
+		}},
+		{"Rates as MessagePack", "GET", "/rates", "", "application/msgpack", "application/msgpack", func(t *testing.T, body []byte) {
+			// A map of five entries, starting with the base
+			if !bytes.HasPrefix(body, []byte{0x85, 0xa4, 'b', 'a', 's', 'e', 0xa3, 'U', 'S', 'D'}) {
+				t.Errorf("Unexpected MessagePack: % x", body)
+			}
+		}},
+		{"Exchange as XML", "GET", "/exchange?from=USD&to=EUR&amount=100", "", "text/xml", "application/xml", func(t *testing.T, body []byte) {
+			var resp ExchangeResponse
+			if err := xml.Unmarshal(body, &resp); err != nil || resp.ConvertedAmount != 85 || resp.To != "EUR" {
+				t.Errorf("Unexpected XML %s: %v", body, err)
+			}
+		}},
+		{"Exchange as CSV", "GET", "/exchange?from=usd&to=gbp&amount=10", "", "text/csv", "text/csv", func(t *testing.T, body []byte) {
+			expectCSV(t, body, [][]string{
+				exchangeCSVHeader,
+				{"USD", "GBP", "10", "7.3", "0.73", "2", "2025-01-01T00:00:00Z", "builtin", ""},
+			})
+		}},
+		{"Batch as JSON", "POST", "/exchange/batch", `{"conversions":[{"from":"USD","to":"EUR","amount":100},{"from":"USD","to":"XYZ","amount":1}]}`, "", "application/json", func(t *testing.T, body []byte) {
+			var resp BatchResponse
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != 2 || resp.Results[0].Conversion.ConvertedAmount != 85 || *resp.Results[1].Error != (BatchError{Code: CodeUnsupportedCurrency, Field: "to", Message: "currency XYZ not supported"}) {
+				t.Errorf("Unexpected batch: %s", body)
+			}
+		}},
+		{"Batch as CSV", "POST", "/exchange/batch?format=csv", `{"conversions":[{"from":"USD","to":"EUR","amount":100},{"to":"EUR","amount":1}]}`, "", "text/csv", func(t *testing.T, body []byte) {
+			expectCSV(t, body, [][]string{
+				append(append([]string(nil), exchangeCSVHeader...), "error_code", "error"),
+				{"USD", "EUR", "100", "85", "0.85", "2", "2025-01-01T00:00:00Z", "builtin", "", "", ""},
+				{"", "EUR", "1", "", "", "", "", "", "", "MISSING_PARAMETER", "Missing required parameter: from"},
+			})
+		}},
+		{"Batch as XML", "POST", "/exchange/batch", `{"conversions":[{"from":"USD","to":"EUR","amount":1},{"from":"USD","to":"EUR","amount":-1}]}`, "application/xml", "application/xml", func(t *testing.T, body []byte) {
+			if !strings.Contains(string(body), "<batch><result><exchange><from>USD</from>") ||
+				!strings.Contains(string(body), "<result><error><code>INVALID_AMOUNT</code><field>amount</field><message>amount must be a positive number</message></error></result></batch>") {
+				t.Errorf("Unexpected XML: %s", body)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != tt.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedType, ct)
+			}
+			if vary := rr.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("Expected Vary: Accept, got %q", vary)
+			}
+			tt.check(t, rr.Body.Bytes())
+		})
+	}
+}
+
+func expectCSV(t *testing.T, body []byte, expected [][]string) {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %q", len(expected), records)
+	}
+	for i := range expected {
+		if strings.Join(records[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Record %d: expected %q, got %q", i, expected[i], records[i])
+		}
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{})
+
+	for _, url := range []string{"/rates", "/exchange?from=USD&to=EUR&amount=1", "/rates?format=yaml"} {
+		req := httptest.NewRequest("GET", url, nil)
+		if !strings.Contains(url, "format") {
+			req.Header.Set("Accept", "text/html")
+		}
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotAcceptable || rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: expected a JSON 406, got %d %q", url, rr.Code, rr.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestExchangeBatchHandlerErrors(t *testing.T) {
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{})
+	tooMany := `{"conversions":[` + strings.Repeat(`{"from":"USD","to":"EUR","amount":1},`, MaxBatchSize) + `{"from":"USD","to":"EUR","amount":1}]}`
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"Malformed body", `{"conversions":`, "Invalid request body"},
+		{"No conversions", `{"conversions":[]}`, "Missing required parameter: conversions"},
+		{"Too many conversions", tooMany, "At most 100 conversions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest("POST", "/exchange/batch", strings.NewReader(tt.body)))
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("Expected 400 mentioning %q, got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/openapi"
+)
+
+// spec builds the OpenAPI document. Its methods mirror the route helpers
+// in RegisterRoutes so the two can be compared.
+type spec struct {
+	doc  *openapi.Document
+	opts RouteOptions
+}
+
+// OpenAPI describes the routes RegisterRoutes adds with opts, with the
+// response schemas derived from the types the handlers encode
+func OpenAPI(opts RouteOptions) *openapi.Document {
+	s := &spec{
+		doc: &openapi.Document{
+			OpenAPI: openapi.Version,
+			Info: openapi.Info{
+				Title: "Currency Exchange API",
+				Description: "Converts amounts between currencies using the active rate snapshot. " +
+					"The unversioned paths are deprecated aliases of /v1.",
+				Version: "2.0.0",
+			},
+			Paths: make(map[string]*openapi.PathItem),
+		},
+		opts: opts,
+	}
+	c := &s.doc.Components
+
+	problem := c.Resolve(c.Schema(ErrorResponse{}))
+	problem.Properties["code"].Enum = errorCodes
+	problem.Properties["type"].Description = "Always about:blank"
+	if opts.Authenticate != nil {
+		c.SecuritySchemes = map[string]*openapi.SecurityScheme{
+			"apiKey": {Type: "apiKey", In: "header", Name: auth.APIKeyHeader, Description: "API key"},
+			"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "JWT access token"},
+		}
+	}
+
+	exchange := []*openapi.Parameter{
+		query("from", "Source currency code, e.g. USD", true, &openapi.Schema{Type: "string"}),
+		query("to", "Target currency code, e.g. EUR", true, &openapi.Schema{Type: "string"}),
+		query("amount", "Amount to convert, a positive number", true, &openapi.Schema{Type: "number"}),
+	}
+	batch := &openapi.RequestBody{
+		Description: "Up to " + strconv.Itoa(MaxBatchSize) + " conversions",
+		Required:    true,
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: c.Schema(BatchRequest{})}},
+	}
+	symbols := query("symbols", "Comma separated currency codes to send; all when omitted", false, &openapi.Schema{Type: "string"})
+	badExchange := errorResponse("Invalid conversion", CodeMissingParameter, CodeInvalidAmount, CodeUnsupportedCurrency)
+	badBatch := errorResponse("Invalid batch", CodeInvalidBody, CodeMissingParameter, CodeBatchTooLarge)
+	stale := errorResponse("Rates are stale under the reject policy", CodeRatesStale)
+
+	s.v1(http.MethodGet, "/exchange", s.convert(&openapi.Operation{
+		OperationID: "convert",
+		Summary:     "Convert an amount between currencies",
+		Tags:        []string{"conversion"},
+		Parameters:  append(exchange, formatParameter(formats)),
+		Responses: map[string]*openapi.Response{
+			"200": content("The conversion", c.Schema(ExchangeResponse{}), formats),
+			"400": badExchange,
+			"503": stale,
+		},
+	}, formats))
+	s.v1(http.MethodPost, "/exchange/batch", s.convert(&openapi.Operation{
+		OperationID: "convertBatch",
+		Summary:     "Convert several amounts against one snapshot",
+		Description: "Entries fail on their own, with the error code an error response would carry.",
+		Tags:        []string{"conversion"},
+		Parameters:  []*openapi.Parameter{formatParameter(formats)},
+		RequestBody: batch,
+		Responses: map[string]*openapi.Response{
+			"200": content("The results in request order", c.Schema(BatchResponse{}), formats),
+			"400": badBatch,
+			"503": stale,
+		},
+	}, formats))
+	s.v1(http.MethodGet, "/rates", s.read(&openapi.Operation{
+		OperationID: "getRates",
+		Summary:     "Get the active rate table",
+		Tags:        []string{"rates"},
+		Parameters:  []*openapi.Parameter{formatParameter(formats)},
+		Responses: map[string]*openapi.Response{
+			"200": content("The rates against the base currency", c.Schema(RatesResponse{}), formats),
+		},
+	}, formats))
+	s.v1(http.MethodGet, "/rates/stream", s.stream(&openapi.Operation{
+		OperationID: "streamRates",
+		Summary:     "Stream rate updates as Server-Sent Events",
+		Description: "Sends a rates event on connect and on every new snapshot, and heartbeat events in between. " +
+			"Each rates event carries a RatesEvent as its data.",
+		Tags:       []string{"rates"},
+		Parameters: []*openapi.Parameter{symbols},
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "An event stream",
+				Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}},
+			},
+		},
+	}))
+	c.Schema(RatesEvent{})
+	s.v1(http.MethodGet, "/rates/ws", s.stream(&openapi.Operation{
+		OperationID: "watchRates",
+		Summary:     "Stream rate updates over a WebSocket",
+		Description: `Send {"action": "subscribe" | "unsubscribe", "symbols": [...]} to change the subscription. ` +
+			"The server sends subscribed, rates, heartbeat and error messages, each a JSON object with a type.",
+		Tags:       []string{"rates"},
+		Parameters: []*openapi.Parameter{symbols},
+		Responses: map[string]*openapi.Response{
+			"101": {Description: "Switched to the WebSocket protocol"},
+			"403": errorResponse("Origin not allowed", CodeForbidden),
+			"426": errorResponse("Not a WebSocket upgrade", CodeUpgradeRequired),
+		},
+	}))
+	if opts.SnapshotAdmin {
+		s.v1(http.MethodGet, "/snapshots", s.admin(&openapi.Operation{
+			OperationID: "listSnapshots",
+			Summary:     "List the installed rate snapshots",
+			Tags:        []string{"snapshots"},
+			Responses: map[string]*openapi.Response{
+				"200": jsonResponse("The snapshots and the active version", c.Schema(SnapshotsResponse{})),
+				"500": errorResponse("The snapshot store failed", CodeInternal),
+			},
+		}))
+		s.v1(http.MethodPost, "/snapshots/{id}/activate", s.admin(&openapi.Operation{
+			OperationID: "activateSnapshot",
+			Summary:     "Roll back to an installed snapshot",
+			Tags:        []string{"snapshots"},
+			Parameters: []*openapi.Parameter{{
+				Name: "id", In: "path", Description: "Snapshot version", Required: true,
+				Schema: &openapi.Schema{Type: "integer", Format: "int64"},
+			}},
+			Responses: map[string]*openapi.Response{
+				"200": jsonResponse("The activated snapshot", c.Schema(ActivationResponse{})),
+				"400": errorResponse("Invalid snapshot id", CodeInvalidParameter),
+				"404": errorResponse("Unknown snapshot version", CodeSnapshotNotFound),
+			},
+		}))
+	}
+
+	v2 := offer("json", "msgpack")
+	s.add(http.MethodGet, "/v2/exchange", s.convert(&openapi.Operation{
+		OperationID: "convertV2",
+		Summary:     "Convert an amount, with decimal strings",
+		Tags:        []string{"conversion"},
+		Parameters:  append(exchange, formatParameter(v2)),
+		Responses: map[string]*openapi.Response{
+			"200": content("The conversion", c.Schema(ExchangeResponseV2{}), v2),
+			"400": badExchange,
+			"503": stale,
+		},
+	}, v2))
+	s.add(http.MethodPost, "/v2/exchange/batch", s.convert(&openapi.Operation{
+		OperationID: "convertBatchV2",
+		Summary:     "Convert several amounts, with decimal strings",
+		Tags:        []string{"conversion"},
+		Parameters:  []*openapi.Parameter{formatParameter(v2)},
+		RequestBody: batch,
+		Responses: map[string]*openapi.Response{
+			"200": content("The results in request order", c.Schema(BatchResponseV2{}), v2),
+			"400": badBatch,
+			"503": stale,
+		},
+	}, v2))
+	s.add(http.MethodGet, "/v2/rates", s.read(&openapi.Operation{
+		OperationID: "getRatesV2",
+		Summary:     "Get the active rate table, with decimal strings",
+		Tags:        []string{"rates"},
+		Parameters:  []*openapi.Parameter{formatParameter(v2)},
+		Responses: map[string]*openapi.Response{
+			"200": content("The rates sorted by currency", c.Schema(RatesResponseV2{}), v2),
+		},
+	}, v2))
+
+	health, probe := c.Schema(HealthResponse{}), c.Schema(ProbeResponse{})
+	s.add(http.MethodGet, "/health", &openapi.Operation{
+		OperationID: "getHealth",
+		Summary:     "Aggregate health: healthy, degraded or unhealthy",
+		Tags:        []string{"operations"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Healthy or degraded", health),
+			"503": jsonResponse("Unhealthy", health),
+		},
+	})
+	s.add(http.MethodGet, "/livez", &openapi.Operation{
+		OperationID: "getLiveness",
+		Summary:     "Liveness probe",
+		Tags:        []string{"operations"},
+		Responses:   map[string]*openapi.Response{"200": jsonResponse("The process is serving HTTP", probe)},
+	})
+	s.add(http.MethodGet, "/readyz", &openapi.Operation{
+		OperationID: "getReadiness",
+		Summary:     "Readiness probe",
+		Tags:        []string{"operations"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Ready to serve conversions", probe),
+			"503": jsonResponse("A dependency check failed", probe),
+		},
+	})
+	s.add(http.MethodGet, "/openapi.json", &openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Tags:        []string{"operations"},
+		Responses:   map[string]*openapi.Response{"200": jsonResponse("An OpenAPI 3.0 document", &openapi.Schema{Type: "object"})},
+	})
+	return s.doc
+}
+
+// add describes the operation at method and path
+func (s *spec) add(method, path string, op *openapi.Operation) {
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		item = &openapi.PathItem{}
+		s.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// v1 describes a /v1 operation and, unless legacy routes are disabled,
+// its deprecated unversioned alias
+func (s *spec) v1(method, path string, op *openapi.Operation) {
+	s.add(method, "/v1"+path, op)
+	if s.opts.Legacy.Disabled {
+		return
+	}
+
+	headers := map[string]*openapi.Header{
+		"Deprecation": {Description: "When the path was deprecated, as @<unix time>, or true", Schema: &openapi.Schema{Type: "string"}},
+		"Link":        {Description: "The /v1 successor, with rel=\"successor-version\"", Schema: &openapi.Schema{Type: "string"}},
+	}
+	if !s.opts.Legacy.Sunset.IsZero() {
+		headers["Sunset"] = &openapi.Header{Description: "When the path will be removed", Schema: &openapi.Schema{Type: "string"}}
+	}
+	legacy := *op
+	legacy.OperationID += "Legacy"
+	legacy.Deprecated = true
+	legacy.Description = "Deprecated alias of /v1" + path + "."
+	legacy.Responses = make(map[string]*openapi.Response, len(op.Responses))
+	for status, response := range op.Responses {
+		with := *response
+		with.Headers = make(map[string]*openapi.Header, len(response.Headers)+len(headers))
+		for name, h := range response.Headers {
+			with.Headers[name] = h
+		}
+		for name, h := range headers {
+			with.Headers[name] = h
+		}
+		legacy.Responses[status] = &with
+	}
+	s.add(method, path, &legacy)
+}
+
+// guard adds the responses and security of the middleware guard adds in
+// RegisterRoutes
+func (s *spec) guard(op *openapi.Operation, scope string) *openapi.Operation {
+	if s.opts.Authenticate != nil {
+		op.Security = []map[string][]string{{"apiKey": {}}, {"bearer": {}}}
+		op.Responses["401"] = errorResponse("Missing or invalid credentials", CodeUnauthorized)
+		op.Responses["403"] = errorResponse("The caller lacks the "+scope+" scope", CodeForbidden)
+	}
+	if s.opts.RateLimit != nil {
+		op.Responses["429"] = limited("X-RateLimit-")
+	}
+	return op
+}
+
+// read is the rates route guard with format negotiation
+func (s *spec) read(op *openapi.Operation, offered []format) *openapi.Operation {
+	op.Responses["406"] = notAcceptable(offered)
+	return s.guard(op, auth.ScopeRead)
+}
+
+// convert is read with the conversion quota
+func (s *spec) convert(op *openapi.Operation, offered []format) *openapi.Operation {
+	s.read(op, offered)
+	if s.opts.Quota != nil {
+		limit := limited("X-RateLimit-")
+		for name, h := range limited("X-Quota-").Headers {
+			limit.Headers[name] = h
+		}
+		limit.Description = "Rate limit or daily quota exceeded"
+		op.Responses["429"] = limit
+	}
+	return op
+}
+
+// stream is the guard of the streaming routes, which are limited in
+// number
+func (s *spec) stream(op *openapi.Operation) *openapi.Operation {
+	op.Responses["400"] = errorResponse("Unknown symbol", CodeUnsupportedCurrency)
+	op.Responses["503"] = errorResponse("Too many streaming clients", CodeUnavailable)
+	return s.guard(op, auth.ScopeRead)
+}
+
+func (s *spec) admin(op *openapi.Operation) *openapi.Operation {
+	return s.guard(op, auth.ScopeAdmin)
+}
+
+func query(name, description string, required bool, schema *openapi.Schema) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Required: required, Schema: schema}
+}
+
+// formatParameter is the format query parameter choosing among offered
+func formatParameter(offered []format) *openapi.Parameter {
+	names := make([]string, len(offered))
+	for i, f := range offered {
+		names[i] = f.name
+	}
+	return query("format", "Response format, overriding the Accept header", false,
+		&openapi.Schema{Type: "string", Enum: names})
+}
+
+// content is a negotiated response: schema in JSON and MessagePack, and a
+// string in XML and CSV
+func content(description string, schema *openapi.Schema, offered []format) *openapi.Response {
+	response := &openapi.Response{Description: description, Content: make(map[string]openapi.MediaType)}
+	for _, f := range offered {
+		switch f.name {
+		case "json", "msgpack":
+			response.Content[f.mediaType] = openapi.MediaType{Schema: schema}
+		default:
+			response.Content[f.mediaType] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+		}
+	}
+	return response
+}
+
+func jsonResponse(description string, schema *openapi.Schema) *openapi.Response {
+	return &openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// errorResponse is a problem document with one of codes
+func errorResponse(description string, codes ...string) *openapi.Response {
+	ref := &openapi.Schema{Ref: "#/components/schemas/ErrorResponse"}
+	return &openapi.Response{
+		Description: description + " (" + strings.Join(codes, ", ") + ")",
+		Content: map[string]openapi.MediaType{
+			"application/json": {Schema: ref},
+			problemMediaType:   {Schema: ref},
+		},
+	}
+}
+
+func notAcceptable(offered []format) *openapi.Response {
+	names := make([]string, len(offered))
+	for i, f := range offered {
+		names[i] = f.mediaType
+	}
+	return errorResponse("None of "+strings.Join(names, ", ")+" is acceptable", CodeNotAcceptable)
+}
+
+// limited is a 429 carrying the headers of the limiter with prefix
+func limited(prefix string) *openapi.Response {
+	response := errorResponse("Rate limit exceeded", CodeRateLimited)
+	integer := &openapi.Schema{Type: "integer"}
+	response.Headers = map[string]*openapi.Header{
+		"Retry-After":        {Description: "Seconds until a request may succeed", Schema: integer},
+		prefix + "Limit":     {Description: "Requests allowed per window", Schema: integer},
+		prefix + "Remaining": {Description: "Requests left in the window", Schema: integer},
+		prefix + "Reset":     {Description: "Unix time the window resets", Schema: integer},
+	}
+	return response
+}
+
+// openAPIHandler serves doc, encoded once
+func openAPIHandler(doc *openapi.Document) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		panic("service: encoding OpenAPI document: " + err.Error())
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}
+}
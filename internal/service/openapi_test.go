@@ -0,0 +1,231 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/openapi"
+	"currency_go_microservice/internal/router"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	pass := func(next http.Handler) http.Handler { return next }
+	tests := []struct {
+		name string
+		opts RouteOptions
+	}{
+		{"Defaults", RouteOptions{}},
+		{"Admin and sunset", RouteOptions{SnapshotAdmin: true, Legacy: LegacyRoutes{Sunset: time.Now()}}},
+		{"Versioned only", RouteOptions{SnapshotAdmin: true, Legacy: LegacyRoutes{Disabled: true}}},
+		{"Guarded", RouteOptions{SnapshotAdmin: true, Authenticate: pass, RateLimit: pass, Quota: pass}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := router.New(WriteError)
+			NewCurrencyService().RegisterRoutes(rt, tt.opts)
+			var registered []string
+			for _, route := range rt.Routes() {
+				registered = append(registered, route.Method+" "+route.Pattern)
+			}
+
+			doc := OpenAPI(tt.opts)
+			var described []string
+			ids := make(map[string]bool)
+			for path, item := range doc.Paths {
+				for method, op := range *item {
+					described = append(described, strings.ToUpper(method)+" "+path)
+					if ids[op.OperationID] {
+						t.Errorf("Duplicate operation ID %s", op.OperationID)
+					}
+					ids[op.OperationID] = true
+					checkOperation(t, doc, tt.opts, path, op)
+				}
+			}
+
+			sort.Strings(registered)
+			sort.Strings(described)
+			if !slices.Equal(registered, described) {
+				t.Errorf("Routes and OpenAPI document differ:\nroutes:   %v\ndocument: %v", registered, described)
+			}
+		})
+	}
+}
+
+// checkOperation checks references resolve and the options are reflected
+func checkOperation(t *testing.T, doc *openapi.Document, opts RouteOptions, path string, op *openapi.Operation) {
+	t.Helper()
+	legacy := !strings.HasPrefix(path, "/v") && op.Deprecated
+	if strings.HasSuffix(op.OperationID, "Legacy") != legacy {
+		t.Errorf("%s: legacy operation %s should be deprecated", path, op.OperationID)
+	}
+	for status, response := range op.Responses {
+		for mediaType, content := range response.Content {
+			if content.Schema != nil && doc.Components.Resolve(content.Schema) == nil {
+				t.Errorf("%s %s %s: unresolved schema %s", path, status, mediaType, content.Schema.Ref)
+			}
+		}
+		if _, ok := response.Headers["Deprecation"]; ok != legacy {
+			t.Errorf("%s %s: Deprecation header documented %v, expected %v", path, status, ok, legacy)
+		}
+		if _, ok := response.Headers["Sunset"]; ok != (legacy && !opts.Legacy.Sunset.IsZero()) {
+			t.Errorf("%s %s: unexpected Sunset header documentation", path, status)
+		}
+	}
+	guarded := path != "/health" && path != "/livez" && path != "/readyz" && path != "/openapi.json"
+	if _, ok := op.Responses["401"]; ok != (guarded && opts.Authenticate != nil) {
+		t.Errorf("%s: 401 documented %v, expected %v", path, ok, guarded && opts.Authenticate != nil)
+	}
+	for _, p := range op.Parameters {
+		if p.In == "path" && !strings.Contains(path, "{"+p.Name+"}") {
+			t.Errorf("%s: path parameter %s is not in the path", path, p.Name)
+		}
+	}
+}
+
+func TestOpenAPIDescribesResponses(t *testing.T) {
+	opts := RouteOptions{SnapshotAdmin: true}
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, opts)
+	doc := OpenAPI(opts)
+
+	tests := []struct {
+		method string
+		url    string
+		path   string
+		body   string
+		status int
+	}{
+		{"GET", "/v1/exchange?from=USD&to=EUR&amount=100", "/v1/exchange", "", http.StatusOK},
+		{"GET", "/v1/exchange?from=USD&to=XYZ&amount=100", "/v1/exchange", "", http.StatusBadRequest},
+		{"GET", "/exchange?from=USD&to=EUR", "/exchange", "", http.StatusBadRequest},
+		{"GET", "/v1/exchange?from=USD&to=EUR&amount=1&format=yaml", "/v1/exchange", "", http.StatusNotAcceptable},
+		{"POST", "/v1/exchange/batch", "/v1/exchange/batch", `{"conversions":[{"from":"USD","to":"EUR","amount":1},{"from":"USD","to":"XYZ","amount":1}]}`, http.StatusOK},
+		{"POST", "/v1/exchange/batch", "/v1/exchange/batch", `{}`, http.StatusBadRequest},
+		{"GET", "/v1/rates", "/v1/rates", "", http.StatusOK},
+		{"GET", "/v1/rates/stream?symbols=XYZ", "/v1/rates/stream", "", http.StatusBadRequest},
+		{"GET", "/v1/rates/ws", "/v1/rates/ws", "", http.StatusUpgradeRequired},
+		{"GET", "/v1/snapshots", "/v1/snapshots", "", http.StatusOK},
+		{"POST", "/v1/snapshots/1/activate", "/v1/snapshots/{id}/activate", "", http.StatusOK},
+		{"POST", "/v1/snapshots/x/activate", "/v1/snapshots/{id}/activate", "", http.StatusBadRequest},
+		{"POST", "/v1/snapshots/9/activate", "/v1/snapshots/{id}/activate", "", http.StatusNotFound},
+		{"GET", "/v2/exchange?from=USD&to=EUR&amount=100", "/v2/exchange", "", http.StatusOK},
+		{"POST", "/v2/exchange/batch", "/v2/exchange/batch", `{"conversions":[{"from":"USD","to":"EUR","amount":1},{"from":"USD","to":"XYZ","amount":1}]}`, http.StatusOK},
+		{"GET", "/v2/rates", "/v2/rates", "", http.StatusOK},
+		{"GET", "/health", "/health", "", http.StatusOK},
+		{"GET", "/livez", "/livez", "", http.StatusOK},
+		{"GET", "/readyz", "/readyz", "", http.StatusOK},
+		{"GET", "/openapi.json", "/openapi.json", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+
+			item, ok := doc.Paths[tt.path]
+			if !ok {
+				t.Fatalf("Path %s is not documented", tt.path)
+			}
+			op := (*item)[strings.ToLower(tt.method)]
+			response, ok := op.Responses[strconv.Itoa(rr.Code)]
+			if !ok {
+				t.Fatalf("Status %d of %s is not documented", rr.Code, op.OperationID)
+			}
+			content, ok := response.Content["application/json"]
+			if !ok {
+				t.Fatalf("%s %d has no JSON content", op.OperationID, rr.Code)
+			}
+
+			var body any
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Invalid JSON: %v", err)
+			}
+			if err := conforms(&doc.Components, content.Schema, body); err != nil {
+				t.Errorf("Response does not match the documented schema: %v\n%s", err, rr.Body.String())
+			}
+		})
+	}
+}
+
+// conforms checks a decoded JSON value against schema: types, enums,
+// required properties and that no undocumented properties are sent
+func conforms(c *openapi.Components, schema *openapi.Schema, v any) error {
+	schema = c.Resolve(schema)
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, fmt.Sprint(v)) {
+		return fmt.Errorf("%v is not one of %v", v, schema.Enum)
+	}
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		object, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected an object, got %T", v)
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("missing required property %s", name)
+			}
+		}
+		for name, value := range object {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property == nil {
+				if schema.Properties == nil {
+					continue
+				}
+				return fmt.Errorf("undocumented property %s", name)
+			}
+			if err := conforms(c, property, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	case "array":
+		array, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("expected an array, got %T", v)
+		}
+		for i, item := range array {
+			if err := conforms(c, schema.Items, item); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("expected a string, got %T", v)
+		}
+	case "number", "integer":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("expected a number, got %T", v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("expected a boolean, got %T", v)
+		}
+	}
+	return nil
+}
+
+func TestOpenAPIErrorCodes(t *testing.T) {
+	doc := OpenAPI(RouteOptions{})
+	code := doc.Components.Schemas["ErrorResponse"].Properties["code"]
+	for status, c := range statusCodes {
+		if !slices.Contains(code.Enum, c) {
+			t.Errorf("Code %s for status %d is not documented", c, status)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"currency_go_microservice/internal/provider"
+	"currency_go_microservice/internal/tracing"
+)
+
+// WithRateProvider sets the upstream source used by Refresh and RunRefresher
+func WithRateProvider(p provider.RateProvider) Option {
+	return func(cs *CurrencyService) { cs.provider = p }
+}
+
+// Refresh fetches rates from the configured provider and installs them as a
+// new snapshot. Tables served from a provider's cache are not reinstalled;
+// the active snapshot already holds them.
+func (cs *CurrencyService) Refresh(ctx context.Context) (err error) {
+	if cs.provider == nil {
+		return errors.New("no rate provider configured")
+	}
+	ctx, span := tracing.Start(ctx, "Refresh", attribute.String("provider.name", cs.provider.Name()))
+	defer func() { tracing.End(span, err) }()
+
+	rates, err := cs.provider.FetchRates(ctx)
+	if err != nil {
+		cs.metrics.refreshes.Inc("failure")
+		return err
+	}
+	if rates.Cached {
+		cs.metrics.refreshes.Inc("cached")
+		slog.WarnContext(ctx, "all rate providers unavailable, keeping active snapshot", "version", cs.ActiveSnapshot().Version)
+		return nil
+	}
+	snapshot := NewRateSnapshot(rates.Base, rates.Rates)
+	snapshot.AsOf = rates.AsOf
+	snapshot.Source = rates.Source
+	snapshot.Consensus = rates.Consensus
+	if err := cs.installSnapshot(ctx, snapshot); err != nil {
+		cs.metrics.refreshes.Inc("failure")
+		return err
+	}
+	cs.metrics.refreshes.Inc("success")
+	return nil
+}
+
+// RunRefresher refreshes rates every interval until ctx is cancelled
+func (cs *CurrencyService) RunRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := cs.Refresh(ctx); err != nil {
+			slog.ErrorContext(ctx, "rate refresh failed", "provider", cs.provider.Name(), "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProviderStatus reports the configured provider's status, if it exposes one
+func (cs *CurrencyService) ProviderStatus() (provider.ProviderStatus, bool) {
+	reporter, ok := cs.provider.(provider.StatusReporter)
+	if !ok {
+		return provider.ProviderStatus{}, false
+	}
+	return reporter.Status(), true
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/provider"
+)
+
+func TestRefreshExposesConsensusMetadata(t *testing.T) {
+	agg := provider.NewAggregator(time.Second,
+		provider.NewStaticProvider("a", "USD", withRate("EUR", 0.84)),
+		provider.NewStaticProvider("b", "USD", withRate("EUR", 0.86)),
+	)
+	cs := NewCurrencyService(WithRateProvider(agg))
+
+	if err := cs.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	cs.RatesHandler(rr, httptest.NewRequest("GET", "/rates", nil))
+
+	var response struct {
+		Rates    map[string]float64 `json:"rates"`
+		Metadata provider.Consensus `json:"metadata"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+
+	if response.Rates["EUR"] != 0.85 {
+		t.Errorf("Expected consensus EUR rate 0.85, got %v", response.Rates["EUR"])
+	}
+	if len(response.Metadata.Providers) != 2 {
+		t.Errorf("Expected 2 contributing providers, got %v", response.Metadata.Providers)
+	}
+	if response.Metadata.Currencies["EUR"].Dispersion == 0 {
+		t.Errorf("Expected non-zero EUR dispersion")
+	}
+}
+
+func TestHealthHandlerReportsProviderStatus(t *testing.T) {
+	primary := provider.NewStaticProvider("primary", "USD", ExchangeRates)
+	fallback := provider.NewFallback(provider.DefaultBreakerConfig(), time.Second, primary)
+	cs := NewCurrencyService(WithRateProvider(fallback))
+
+	if err := cs.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	cs.HealthHandler(rr, httptest.NewRequest("GET", "/health", nil))
+
+	var response struct {
+		Status      string                   `json:"status"`
+		RatesSource string                   `json:"rates_source"`
+		Providers   []provider.BreakerStatus `json:"providers"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+
+	if response.RatesSource != "primary" {
+		t.Errorf("Expected rates source 'primary', got '%s'", response.RatesSource)
+	}
+	if len(response.Providers) != 1 || response.Providers[0].State != provider.BreakerClosed {
+		t.Errorf("Expected one closed breaker, got %+v", response.Providers)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/router"
+)
+
+// RouteOptions controls which routes are registered and how they are guarded
+type RouteOptions struct {
+	// SnapshotAdmin registers the /snapshots admin endpoints
+	SnapshotAdmin bool
+	// Authenticate, when set, runs before the conversion, rates and admin
+	// routes, which then require auth.ScopeRead or auth.ScopeAdmin. The
+	// probes stay open.
+	Authenticate router.Middleware
+	// RateLimit, when set, runs after Authenticate on the same routes
+	RateLimit router.Middleware
+	// Quota, when set, counts conversions against the caller's quota; a
+	// batch counts each of its conversions, see ratelimit.WithCost
+	Quota router.Middleware
+	// Legacy controls the unversioned aliases of the /v1 routes
+	Legacy LegacyRoutes
+}
+
+// LegacyRoutes configures the unversioned paths, which serve the /v1 API
+// with deprecation headers so clients can find and migrate their calls
+type LegacyRoutes struct {
+	// Disabled leaves only the versioned paths
+	Disabled bool
+	// DeprecatedAt is sent in the Deprecation header; without it the
+	// header is "true"
+	DeprecatedAt time.Time
+	// Sunset, when set, is sent in the Sunset header as the date the
+	// aliases go away
+	Sunset time.Time
+}
+
+// deprecated marks responses on a legacy path with Deprecation and Sunset
+// headers and links the /v1 successor
+func deprecated(legacy LegacyRoutes) router.Middleware {
+	deprecation := "true"
+	if !legacy.DeprecatedAt.IsZero() {
+		deprecation = "@" + strconv.FormatInt(legacy.DeprecatedAt.Unix(), 10)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if !legacy.Sunset.IsZero() {
+				h.Set("Sunset", legacy.Sunset.UTC().Format(http.TimeFormat))
+			}
+			h.Add("Link", "</v1"+r.URL.EscapedPath()+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RegisterRoutes adds the service endpoints to rt: the API under /v1 and,
+// unless disabled, at the deprecated unversioned paths, the v2 API under
+// /v2, the probes and the OpenAPI document. Routes added here must be
+// described in OpenAPI too.
+func (cs *CurrencyService) RegisterRoutes(rt *router.Router, opts RouteOptions) {
+	// guard returns the middleware that authenticates, rate limits and
+	// checks scope, after first
+	guard := func(first router.Middleware, scope string) []router.Middleware {
+		var mws []router.Middleware
+		if first != nil {
+			mws = append(mws, first)
+		}
+		if opts.Authenticate != nil {
+			mws = append(mws, opts.Authenticate)
+		}
+		if opts.RateLimit != nil {
+			mws = append(mws, opts.RateLimit)
+		}
+		if opts.Authenticate != nil {
+			mws = append(mws, auth.RequireScope(WriteError, scope))
+		}
+		return mws
+	}
+	// Conversions and rates negotiate their format; streams set their own
+	// Content-Type. cost runs before the quota to set what a request counts
+	// for.
+	convert := func(negotiate router.Middleware, cost ...router.Middleware) []router.Middleware {
+		mws := guard(negotiate, auth.ScopeRead)
+		if opts.Quota != nil {
+			mws = append(append(mws, cost...), opts.Quota)
+		}
+		return mws
+	}
+	read, admin := guard(negotiate, auth.ScopeRead), guard(router.JSON, auth.ScopeAdmin)
+	stream := guard(nil, auth.ScopeRead)
+
+	v1 := func(method, path string, h http.HandlerFunc, mws ...router.Middleware) {
+		rt.HandleFunc(method, "/v1"+path, h, mws...)
+		if !opts.Legacy.Disabled {
+			rt.HandleFunc(method, path, h, append([]router.Middleware{deprecated(opts.Legacy)}, mws...)...)
+		}
+	}
+	v1(http.MethodGet, "/exchange", cs.ExchangeHandler, convert(negotiate)...)
+	v1(http.MethodPost, "/exchange/batch", cs.ExchangeBatchHandler, convert(negotiate, batchCost)...)
+	v1(http.MethodGet, "/rates", cs.RatesHandler, read...)
+	v1(http.MethodGet, "/rates/stream", cs.RatesStreamHandler, stream...)
+	v1(http.MethodGet, "/rates/ws", cs.RatesWebSocketHandler, stream...)
+	// A HEAD request would hold a stream open without ever sending a body
+	rt.NoHead("/v1/rates/stream", "/v1/rates/ws", "/rates/stream", "/rates/ws")
+	if opts.SnapshotAdmin {
+		v1(http.MethodGet, "/snapshots", cs.SnapshotsHandler, admin...)
+		v1(http.MethodPost, "/snapshots/{id}/activate", cs.ActivateSnapshotHandler, admin...)
+	}
+
+	rt.HandleFunc(http.MethodGet, "/v2/exchange", cs.ExchangeV2Handler, convert(negotiateV2)...)
+	rt.HandleFunc(http.MethodPost, "/v2/exchange/batch", cs.ExchangeBatchV2Handler, convert(negotiateV2, batchCost)...)
+	rt.HandleFunc(http.MethodGet, "/v2/rates", cs.RatesV2Handler, guard(negotiateV2, auth.ScopeRead)...)
+
+	rt.HandleFunc(http.MethodGet, "/health", cs.HealthHandler, router.JSON)
+	rt.HandleFunc(http.MethodGet, "/livez", cs.LivenessHandler, router.JSON)
+	rt.HandleFunc(http.MethodGet, "/readyz", cs.ReadinessHandler, router.JSON)
+	rt.HandleFunc(http.MethodGet, "/openapi.json", openAPIHandler(OpenAPI(opts)), router.JSON)
+}
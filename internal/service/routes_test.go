@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currency_go_microservice/internal/auth"
+	"currency_go_microservice/internal/ratelimit"
+	"currency_go_microservice/internal/router"
+)
+
+func TestRoutesRequireAuthentication(t *testing.T) {
+	store, err := auth.NewMemoryKeyStore(
+		auth.APIKey{ClientID: "billing", Hash: auth.HashKey("reader-key"), Scopes: []string{auth.ScopeRead}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{
+		SnapshotAdmin: true,
+		Authenticate:  auth.Middleware(WriteError, auth.NewAPIKeyAuthenticator(store)),
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		key            string
+		expectedStatus int
+	}{
+		{"Exchange without key", "GET", "/exchange?from=USD&to=EUR&amount=1", "", http.StatusUnauthorized},
+		{"Exchange with key", "GET", "/exchange?from=USD&to=EUR&amount=1", "reader-key", http.StatusOK},
+		{"Rates with key", "GET", "/rates", "reader-key", http.StatusOK},
+		{"Admin without admin scope", "GET", "/snapshots", "reader-key", http.StatusForbidden},
+		{"Liveness stays open", "GET", "/livez", "", http.StatusOK},
+		{"Readiness stays open", "GET", "/readyz", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.key != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.key)
+			}
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestBatchesChargeEachConversion(t *testing.T) {
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Plans:         map[string]ratelimit.Plan{"metered": {RequestsPerSecond: 100, Burst: 100, DailyQuota: 5}},
+		DefaultPlan:   "metered",
+		AnonymousPlan: "metered",
+	}, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{Quota: limiter.Quota(WriteError)})
+
+	entry := `{"from":"USD","to":"EUR","amount":1}`
+	batch := func(n int) string {
+		return `{"conversions":[` + strings.Repeat(entry+",", n-1) + entry + `]}`
+	}
+	tests := []struct {
+		name              string
+		method            string
+		url               string
+		body              string
+		expectedStatus    int
+		expectedRemaining string
+	}{
+		{"Batch of three", "POST", "/v1/exchange/batch", batch(3), http.StatusOK, "2"},
+		{"Single conversion", "GET", "/v1/exchange?from=USD&to=EUR&amount=1", "", http.StatusOK, "1"},
+		{"Batch over what is left", "POST", "/v2/exchange/batch", batch(2), http.StatusTooManyRequests, "1"},
+		{"Batch within what is left", "POST", "/v2/exchange/batch", batch(1), http.StatusOK, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("X-Quota-Remaining"); got != tt.expectedRemaining {
+				t.Errorf("Expected X-Quota-Remaining %s, got %s", tt.expectedRemaining, got)
+			}
+		})
+	}
+}
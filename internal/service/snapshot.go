@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"currency_go_microservice/internal/provider"
+)
+
+// maxQuarantined caps how many rejected snapshots are kept for inspection
+const maxQuarantined = 20
+
+// RateSnapshot is a complete rate table quoted against a base currency.
+// Version and InstalledAt are assigned when the snapshot is installed.
+// AsOf is when the source published the rates, defaulting to the install
+// time. Consensus is set when the table was aggregated from several
+// providers.
+type RateSnapshot struct {
+	Version     uint64              `json:"version"`
+	Base        string              `json:"base"`
+	Rates       map[string]float64  `json:"rates"`
+	AsOf        time.Time           `json:"as_of"`
+	Source      string              `json:"source"`
+	InstalledAt time.Time           `json:"installed_at"`
+	Consensus   *provider.Consensus `json:"consensus,omitempty"`
+}
+
+// NewRateSnapshot builds a snapshot from a rate map, normalising currency codes
+func NewRateSnapshot(base string, rates map[string]float64) *RateSnapshot {
+	normalised := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		normalised[strings.ToUpper(code)] = rate
+	}
+	return &RateSnapshot{Base: strings.ToUpper(base), Rates: normalised}
+}
+
+// clone returns a deep copy so callers cannot mutate an installed table
+func (s *RateSnapshot) clone() *RateSnapshot {
+	c := NewRateSnapshot(s.Base, s.Rates)
+	c.Version = s.Version
+	c.AsOf = s.AsOf
+	c.Source = s.Source
+	c.InstalledAt = s.InstalledAt
+	c.Consensus = s.Consensus
+	return c
+}
+
+// QuarantinedSnapshot records a snapshot rejected by the anomaly guard
+type QuarantinedSnapshot struct {
+	Snapshot   *RateSnapshot
+	Violations []Violation
+	At         time.Time
+}
+
+// snapshotHolder keeps the active rate table and the quarantine list
+type snapshotHolder struct {
+	mu          sync.RWMutex
+	active      *RateSnapshot
+	version     uint64
+	quarantine  []QuarantinedSnapshot
+	quarantined uint64
+}
+
+// ActiveSnapshot returns the rate table currently used for conversions
+func (cs *CurrencyService) ActiveSnapshot() *RateSnapshot {
+	cs.snapshots.mu.RLock()
+	defer cs.snapshots.mu.RUnlock()
+	return cs.snapshots.active
+}
+
+// InstallSnapshot validates a new rate table, assigns it the next version
+// and makes it active. Snapshots failing the anomaly guard are quarantined
+// and the previous table stays in place; the returned error is an
+// *AnomalyError.
+func (cs *CurrencyService) InstallSnapshot(next *RateSnapshot) error {
+	return cs.installSnapshot(context.Background(), next)
+}
+
+func (cs *CurrencyService) installSnapshot(ctx context.Context, next *RateSnapshot) error {
+	if next == nil || len(next.Rates) == 0 {
+		return errors.New("snapshot has no rates")
+	}
+	next = next.clone()
+	if rate, ok := next.Rates[next.Base]; !ok || rate != 1.0 {
+		return fmt.Errorf("snapshot base %s must have a rate of 1", next.Base)
+	}
+
+	h := &cs.snapshots
+	h.mu.Lock()
+	violations := cs.guard.Check(h.active, next)
+	if len(violations) == 0 {
+		defer h.mu.Unlock()
+		next.Version = h.version + 1
+		next.InstalledAt = cs.now().UTC()
+		if next.AsOf.IsZero() {
+			next.AsOf = next.InstalledAt
+		}
+		if err := cs.saveSnapshot(ctx, next); err != nil {
+			return fmt.Errorf("failed to store snapshot: %w", err)
+		}
+		h.version = next.Version
+		h.active = next
+		cs.watchers.publish(next)
+		return nil
+	}
+
+	q := QuarantinedSnapshot{Snapshot: next, Violations: violations, At: cs.now().UTC()}
+	h.quarantine = append(h.quarantine, q)
+	if len(h.quarantine) > maxQuarantined {
+		h.quarantine = h.quarantine[len(h.quarantine)-maxQuarantined:]
+	}
+	h.quarantined++
+	h.mu.Unlock()
+	cs.metrics.quarantined.Inc()
+
+	err := &AnomalyError{Violations: violations}
+	slog.ErrorContext(ctx, "rate snapshot quarantined", "alert", true, "error", err, "violations", len(violations))
+	if cs.onQuarantine != nil {
+		cs.onQuarantine(q)
+	}
+	return err
+}
+
+// ActivateSnapshot makes a previously installed snapshot active again.
+// It bypasses the anomaly guard so a bad table can be rolled back instantly.
+func (cs *CurrencyService) ActivateSnapshot(ctx context.Context, version uint64) (*RateSnapshot, error) {
+	snapshot, err := cs.getSnapshot(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.snapshots.mu.Lock()
+	cs.snapshots.active = snapshot
+	cs.watchers.publish(snapshot)
+	cs.snapshots.mu.Unlock()
+	slog.WarnContext(ctx, "rate snapshot activated", "version", version)
+	return snapshot, nil
+}
+
+// Snapshots lists the installed snapshots retained by the store
+func (cs *CurrencyService) Snapshots(ctx context.Context) ([]*RateSnapshot, error) {
+	return cs.listSnapshots(ctx)
+}
+
+// Quarantined returns the most recently rejected snapshots, oldest first
+func (cs *CurrencyService) Quarantined() []QuarantinedSnapshot {
+	cs.snapshots.mu.RLock()
+	defer cs.snapshots.mu.RUnlock()
+	return append([]QuarantinedSnapshot(nil), cs.snapshots.quarantine...)
+}
+
+// QuarantineCount returns how many snapshots have been rejected since startup
+func (cs *CurrencyService) QuarantineCount() uint64 {
+	cs.snapshots.mu.RLock()
+	defer cs.snapshots.mu.RUnlock()
+	return cs.snapshots.quarantined
+}
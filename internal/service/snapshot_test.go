@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"currency_go_microservice/internal/router"
+)
+
+func TestSnapshotVersioning(t *testing.T) {
+	cs := NewCurrencyService()
+
+	if v := cs.ActiveSnapshot().Version; v != 1 {
+		t.Fatalf("Expected seed snapshot version 1, got %d", v)
+	}
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("EUR", 0.9))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("EUR", 85))); err == nil {
+		t.Fatalf("Expected anomalous snapshot to be rejected")
+	}
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("EUR", 0.95))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := cs.ActiveSnapshot().Version; v != 3 {
+		t.Errorf("Expected quarantined snapshot not to consume a version, active is %d", v)
+	}
+
+	req := httptest.NewRequest("GET", "/exchange?from=USD&to=EUR&amount=100", nil)
+	rr := httptest.NewRecorder()
+	cs.ExchangeHandler(rr, req)
+
+	var resp ExchangeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if resp.Version != 3 {
+		t.Errorf("Expected response version 3, got %d", resp.Version)
+	}
+}
+
+func TestSnapshotHandlers(t *testing.T) {
+	cs := NewCurrencyService()
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", withRate("EUR", 0.9))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rt := router.New(WriteError)
+	cs.RegisterRoutes(rt, RouteOptions{SnapshotAdmin: true})
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		expectedActive uint64
+	}{
+		{
+			name:           "List snapshots",
+			method:         "GET",
+			url:            "/snapshots",
+			expectedStatus: http.StatusOK,
+			expectedActive: 2,
+		},
+		{
+			name:           "Roll back to seed snapshot",
+			method:         "POST",
+			url:            "/snapshots/1/activate",
+			expectedStatus: http.StatusOK,
+			expectedActive: 1,
+		},
+		{
+			name:           "Unknown snapshot",
+			method:         "POST",
+			url:            "/snapshots/42/activate",
+			expectedStatus: http.StatusNotFound,
+			expectedActive: 1,
+		},
+		{
+			name:           "Invalid snapshot id",
+			method:         "POST",
+			url:            "/snapshots/abc/activate",
+			expectedStatus: http.StatusBadRequest,
+			expectedActive: 1,
+		},
+		{
+			name:           "GET activate not allowed",
+			method:         "GET",
+			url:            "/snapshots/2/activate",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedActive: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Code == http.StatusOK {
+				var body map[string]interface{}
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Could not parse JSON response: %v", err)
+				}
+				if body["active"] != float64(tt.expectedActive) {
+					t.Errorf("Expected active version %d in response, got %v", tt.expectedActive, body["active"])
+				}
+			}
+			if v := cs.ActiveSnapshot().Version; v != tt.expectedActive {
+				t.Errorf("Expected active version %d, got %d", tt.expectedActive, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// StalePolicy decides what happens to conversions when the active snapshot
+// is older than the configured max age
+type StalePolicy string
+
+const (
+	// StaleWarn serves the conversion with a warning field
+	StaleWarn StalePolicy = "warn"
+	// StaleReject refuses the conversion with 503 Service Unavailable
+	StaleReject StalePolicy = "reject"
+)
+
+// WithMaxAge enables staleness detection. Snapshots whose as_of time is
+// older than maxAge are handled according to policy and make the service
+// report degraded health. A zero maxAge disables the check.
+func WithMaxAge(maxAge time.Duration, policy StalePolicy) Option {
+	return func(cs *CurrencyService) {
+		cs.maxAge = maxAge
+		cs.stalePolicy = policy
+	}
+}
+
+// SnapshotAge returns how old the rates in a snapshot are
+func (cs *CurrencyService) SnapshotAge(s *RateSnapshot) time.Duration {
+	return cs.now().Sub(s.AsOf)
+}
+
+// IsStale reports whether a snapshot is older than the configured max age
+func (cs *CurrencyService) IsStale(s *RateSnapshot) bool {
+	return cs.maxAge > 0 && cs.SnapshotAge(s) > cs.maxAge
+}
+
+// StaleWarning returns the warning attached to responses served from s, or
+// "" when s is fresh
+func (cs *CurrencyService) StaleWarning(s *RateSnapshot) string {
+	if !cs.IsStale(s) {
+		return ""
+	}
+	return cs.staleMessage(s)
+}
+
+// staleMessage describes a stale snapshot for warnings and errors
+func (cs *CurrencyService) staleMessage(s *RateSnapshot) string {
+	return ErrRatesStale.Error() + ": " + cs.staleDetail(s)
+}
+
+func (cs *CurrencyService) staleDetail(s *RateSnapshot) string {
+	return fmt.Sprintf("as of %s, older than max age %s", s.AsOf.Format(time.RFC3339), cs.maxAge)
+}
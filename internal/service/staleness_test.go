@@ -0,0 +1,109 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStaleSnapshot(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         StalePolicy
+		age            time.Duration
+		expectedStatus int
+		expectWarning  bool
+		expectedHealth string
+	}{
+		{
+			name:           "Fresh snapshot",
+			policy:         StaleReject,
+			age:            time.Minute,
+			expectedStatus: http.StatusOK,
+			expectWarning:  false,
+			expectedHealth: "healthy",
+		},
+		{
+			name:           "Stale snapshot with warn policy",
+			policy:         StaleWarn,
+			age:            2 * time.Hour,
+			expectedStatus: http.StatusOK,
+			expectWarning:  true,
+			expectedHealth: "degraded",
+		},
+		{
+			name:           "Stale snapshot with reject policy",
+			policy:         StaleReject,
+			age:            2 * time.Hour,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedHealth: "unhealthy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := NewCurrencyService(WithMaxAge(time.Hour, tt.policy))
+			snapshot := NewRateSnapshot("USD", ExchangeRates)
+			snapshot.Source = "test-feed"
+			snapshot.AsOf = time.Now().Add(-tt.age)
+			if err := cs.InstallSnapshot(snapshot); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			cs.ExchangeHandler(rr, httptest.NewRequest("GET", "/exchange?from=USD&to=EUR&amount=100", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Code == http.StatusOK {
+				var resp ExchangeResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Could not parse JSON response: %v", err)
+				}
+				if resp.Source != "test-feed" {
+					t.Errorf("Expected source 'test-feed', got '%s'", resp.Source)
+				}
+				if !resp.AsOf.Equal(snapshot.AsOf) {
+					t.Errorf("Expected as_of %v, got %v", snapshot.AsOf, resp.AsOf)
+				}
+				if (resp.Warning != "") != tt.expectWarning {
+					t.Errorf("Expected warning %v, got '%s'", tt.expectWarning, resp.Warning)
+				}
+			}
+
+			rr = httptest.NewRecorder()
+			cs.HealthHandler(rr, httptest.NewRequest("GET", "/health", nil))
+			var health map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &health); err != nil {
+				t.Fatalf("Could not parse JSON response: %v", err)
+			}
+			if health["status"] != tt.expectedHealth {
+				t.Errorf("Expected health status '%s', got '%v'", tt.expectedHealth, health["status"])
+			}
+		})
+	}
+}
+
+func TestRatesHandlerIncludesAsOfAndSource(t *testing.T) {
+	cs := NewCurrencyService()
+
+	rr := httptest.NewRecorder()
+	cs.RatesHandler(rr, httptest.NewRequest("GET", "/rates", nil))
+
+	var response struct {
+		AsOf   time.Time `json:"as_of"`
+		Source string    `json:"source"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if response.Source != "builtin" {
+		t.Errorf("Expected source 'builtin', got '%s'", response.Source)
+	}
+	if response.AsOf.IsZero() {
+		t.Errorf("Expected as_of to be set")
+	}
+}
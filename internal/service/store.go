@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"currency_go_microservice/internal/tracing"
+)
+
+// ErrSnapshotNotFound is returned when a snapshot version is unknown to the store
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotStore keeps installed rate snapshots so they can be listed and
+// re-activated later
+type SnapshotStore interface {
+	Save(ctx context.Context, s *RateSnapshot) error
+	Get(ctx context.Context, version uint64) (*RateSnapshot, error)
+	List(ctx context.Context) ([]*RateSnapshot, error)
+}
+
+// MemorySnapshotStore is an in-process SnapshotStore that keeps the most
+// recent snapshots up to a fixed limit
+type MemorySnapshotStore struct {
+	mu        sync.RWMutex
+	limit     int
+	snapshots []*RateSnapshot
+}
+
+// NewMemorySnapshotStore creates a store retaining at most limit snapshots.
+// A limit of zero or less keeps every snapshot.
+func NewMemorySnapshotStore(limit int) *MemorySnapshotStore {
+	return &MemorySnapshotStore{limit: limit}
+}
+
+// Save appends a snapshot, evicting the oldest one when the limit is reached
+func (m *MemorySnapshotStore) Save(ctx context.Context, s *RateSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots = append(m.snapshots, s)
+	if m.limit > 0 && len(m.snapshots) > m.limit {
+		m.snapshots = m.snapshots[len(m.snapshots)-m.limit:]
+	}
+	return nil
+}
+
+// Get returns the snapshot with the given version
+func (m *MemorySnapshotStore) Get(ctx context.Context, version uint64) (*RateSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, s := range m.snapshots {
+		if s.Version == version {
+			return s, nil
+		}
+	}
+	return nil, ErrSnapshotNotFound
+}
+
+// List returns all retained snapshots ordered by version
+func (m *MemorySnapshotStore) List(ctx context.Context) ([]*RateSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := append([]*RateSnapshot(nil), m.snapshots...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// saveSnapshot, getSnapshot and listSnapshots call the store inside a span
+
+func (cs *CurrencyService) saveSnapshot(ctx context.Context, s *RateSnapshot) (err error) {
+	ctx, span := tracing.Start(ctx, "SnapshotStore.Save", attribute.Int64("snapshot.version", int64(s.Version)))
+	defer func() { tracing.End(span, err) }()
+	return cs.store.Save(ctx, s)
+}
+
+func (cs *CurrencyService) getSnapshot(ctx context.Context, version uint64) (s *RateSnapshot, err error) {
+	ctx, span := tracing.Start(ctx, "SnapshotStore.Get", attribute.Int64("snapshot.version", int64(version)))
+	defer func() { tracing.End(span, err) }()
+	return cs.store.Get(ctx, version)
+}
+
+func (cs *CurrencyService) listSnapshots(ctx context.Context) (list []*RateSnapshot, err error) {
+	ctx, span := tracing.Start(ctx, "SnapshotStore.List")
+	defer func() { tracing.End(span, err) }()
+	return cs.store.List(ctx)
+}
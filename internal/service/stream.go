@@ -0,0 +1,428 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"currency_go_microservice/internal/websocket"
+)
+
+// Stream transports, used as metric labels
+const (
+	transportSSE       = "sse"
+	transportWebSocket = "websocket"
+)
+
+// StreamOptions configures the rate streaming endpoints
+type StreamOptions struct {
+	// Heartbeat is how often streams get a heartbeat message
+	Heartbeat time.Duration
+	// WriteTimeout is how long a client may take to accept a message
+	// before it is disconnected as a slow consumer
+	WriteTimeout time.Duration
+	// MaxClients caps concurrent streams across both transports; 0 means
+	// no limit
+	MaxClients int
+	// CheckOrigin decides on cross-origin WebSocket handshakes; nil only
+	// allows same-host pages
+	CheckOrigin func(origin string) bool
+}
+
+// DefaultStreamOptions returns the streaming settings used when none are
+// configured
+func DefaultStreamOptions() StreamOptions {
+	return StreamOptions{
+		Heartbeat:    15 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		MaxClients:   1000,
+	}
+}
+
+// WithStreamOptions replaces the default streaming settings
+func WithStreamOptions(opts StreamOptions) Option {
+	return func(cs *CurrencyService) { cs.streams.opts = opts }
+}
+
+// streams tracks the connected streaming clients
+type streams struct {
+	opts StreamOptions
+
+	mu       sync.Mutex
+	clients  map[string]int
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// acquire registers a client, failing once MaxClients are connected
+func (s *streams) acquire(transport string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, n := range s.clients {
+		total += n
+	}
+	if s.opts.MaxClients > 0 && total >= s.opts.MaxClients {
+		return false
+	}
+	s.clients[transport]++
+	return true
+}
+
+func (s *streams) release(transport string) {
+	s.mu.Lock()
+	s.clients[transport]--
+	s.mu.Unlock()
+}
+
+func (s *streams) count(transport string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clients[transport]
+}
+
+// StopStreams ends every open rate stream, telling WebSocket clients the
+// server is going away. Register it with http.Server.RegisterOnShutdown so
+// long-lived streams do not hold up draining.
+func (cs *CurrencyService) StopStreams() {
+	cs.streams.stopOnce.Do(func() { close(cs.streams.done) })
+}
+
+// RatesEvent is a rate table pushed to streaming clients. Type is only set
+// on WebSocket messages; SSE carries it as the event name.
+type RatesEvent struct {
+	Type    string             `json:"type,omitempty"`
+	Base    string             `json:"base"`
+	Rates   map[string]float64 `json:"rates"`
+	Version uint64             `json:"version"`
+	AsOf    time.Time          `json:"as_of"`
+	Source  string             `json:"source"`
+	Warning string             `json:"warning,omitempty"`
+}
+
+// ratesEvent builds the event for a snapshot, keeping only the symbols in
+// filter unless it is nil
+func (cs *CurrencyService) ratesEvent(snapshot *RateSnapshot, filter map[string]bool) RatesEvent {
+	rates := snapshot.Rates
+	if filter != nil {
+		rates = make(map[string]float64, len(filter))
+		for code := range filter {
+			if rate, ok := snapshot.Rates[code]; ok {
+				rates[code] = rate
+			}
+		}
+	}
+	return RatesEvent{
+		Base:    snapshot.Base,
+		Rates:   rates,
+		Version: snapshot.Version,
+		AsOf:    snapshot.AsOf,
+		Source:  snapshot.Source,
+		Warning: cs.StaleWarning(snapshot),
+	}
+}
+
+// parseSymbols normalises currency codes, rejecting ones the active
+// snapshot does not have
+func (cs *CurrencyService) parseSymbols(symbols []string) (map[string]bool, error) {
+	rates := cs.ActiveSnapshot().Rates
+	set := make(map[string]bool, len(symbols))
+	for _, sym := range symbols {
+		sym = strings.ToUpper(strings.TrimSpace(sym))
+		if sym == "" {
+			continue
+		}
+		if _, ok := rates[sym]; !ok {
+			return nil, &UnsupportedCurrencyError{Currency: sym, Field: "symbols"}
+		}
+		set[sym] = true
+	}
+	return set, nil
+}
+
+// symbolsQuery reads the comma separated symbols parameter; nil means all
+func (cs *CurrencyService) symbolsQuery(r *http.Request) (map[string]bool, error) {
+	raw := r.URL.Query().Get("symbols")
+	if raw == "" {
+		return nil, nil
+	}
+	return cs.parseSymbols(strings.Split(raw, ","))
+}
+
+// slowConsumer reports whether a write failed because the client stopped
+// reading, counting it if so
+func (cs *CurrencyService) slowConsumer(transport string, err error) bool {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	cs.metrics.slowConsumers.Inc(transport)
+	return true
+}
+
+// RatesStreamHandler streams the rate table as Server-Sent Events. It sends
+// the active table as a "rates" event, then one whenever a new snapshot
+// becomes active, and a "heartbeat" event in between. The symbols
+// parameter limits the currencies sent.
+func (cs *CurrencyService) RatesStreamHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := cs.symbolsQuery(r)
+	if err != nil {
+		WriteProblem(w, r, err)
+		return
+	}
+	if !cs.streams.acquire(transportSSE) {
+		WriteError(w, r, http.StatusServiceUnavailable, "Too many streaming clients")
+		return
+	}
+	defer cs.streams.release(transportSSE)
+
+	updates, cancel := cs.Subscribe()
+	defer cancel()
+
+	opts := cs.streams.opts
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event, id string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if id != "" {
+			fmt.Fprintf(&buf, "id: %s\n", id)
+		}
+		fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", event, data)
+		// Extends the server's write timeout for as long as the stream lives
+		rc.SetWriteDeadline(time.Now().Add(opts.WriteTimeout))
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	sendRates := func(s *RateSnapshot) error {
+		return send("rates", fmt.Sprint(s.Version), cs.ratesEvent(s, filter))
+	}
+
+	last := cs.ActiveSnapshot()
+	err = sendRates(last)
+	heartbeat := time.NewTicker(opts.Heartbeat)
+	defer heartbeat.Stop()
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-cs.streams.done:
+			return
+		case s := <-updates:
+			if s != last {
+				last = s
+				err = sendRates(s)
+			}
+		case now := <-heartbeat.C:
+			err = send("heartbeat", "", map[string]time.Time{"time": now.UTC()})
+		}
+	}
+	if cs.slowConsumer(transportSSE, err) {
+		slog.WarnContext(r.Context(), "disconnected slow rate stream consumer", "transport", transportSSE)
+	}
+}
+
+// wsCommand is a message sent by WebSocket clients
+type wsCommand struct {
+	Action  string   `json:"action"`
+	Symbols []string `json:"symbols"`
+}
+
+// wsSubscription is the set of symbols a WebSocket client receives
+type wsSubscription struct {
+	all     bool
+	symbols map[string]bool
+}
+
+func (s *wsSubscription) active() bool { return s.all || len(s.symbols) > 0 }
+
+// filter returns the symbols to send, nil for all
+func (s *wsSubscription) filter() map[string]bool {
+	if s.all {
+		return nil
+	}
+	return s.symbols
+}
+
+func (s *wsSubscription) list() []string {
+	if s.all {
+		return []string{"*"}
+	}
+	list := make([]string, 0, len(s.symbols))
+	for sym := range s.symbols {
+		list = append(list, sym)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// RatesWebSocketHandler streams rates over a WebSocket. Clients send
+// {"action":"subscribe","symbols":[...]} and {"action":"unsubscribe",...};
+// an empty subscribe list means every currency. The server answers with
+// "subscribed" messages listing the current subscription, sends a "rates"
+// message for the subscribed symbols right away and whenever a new
+// snapshot becomes active, and sends "heartbeat" messages and pings in
+// between. The symbols parameter subscribes at connect time.
+func (cs *CurrencyService) RatesWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := cs.symbolsQuery(r)
+	if err != nil {
+		WriteProblem(w, r, err)
+		return
+	}
+	if !cs.streams.acquire(transportWebSocket) {
+		WriteError(w, r, http.StatusServiceUnavailable, "Too many streaming clients")
+		return
+	}
+	defer cs.streams.release(transportWebSocket)
+
+	opts := cs.streams.opts
+	conn, err := websocket.Upgrade(w, r, opts.CheckOrigin)
+	var handshakeErr *websocket.HandshakeError
+	if errors.As(err, &handshakeErr) {
+		WriteError(w, r, handshakeErr.Status, handshakeErr.Message)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "websocket upgrade failed", "error", err)
+		return
+	}
+	conn.WriteTimeout = opts.WriteTimeout
+	conn.IdleTimeout = 2 * opts.Heartbeat
+	conn.MaxMessageSize = 4 << 10
+
+	updates, cancel := cs.Subscribe()
+	defer cancel()
+
+	// The reader hands commands to the loop below, which does all writes
+	// except pongs so messages go out in order
+	commands := make(chan wsCommand)
+	readDone := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				readDone <- err
+				return
+			}
+			var cmd wsCommand
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				cmd = wsCommand{Action: "invalid"}
+			}
+			select {
+			case commands <- cmd:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	sub := &wsSubscription{symbols: map[string]bool{}}
+	if r.URL.Query().Has("symbols") {
+		sub.all = filter == nil
+		if filter != nil {
+			sub.symbols = filter
+		}
+	}
+	last := cs.ActiveSnapshot()
+	sendRates := func(s *RateSnapshot) error {
+		if !sub.active() {
+			return nil
+		}
+		event := cs.ratesEvent(s, sub.filter())
+		event.Type = "rates"
+		return conn.WriteJSON(event)
+	}
+
+	err = sendRates(last)
+	heartbeat := time.NewTicker(opts.Heartbeat)
+	defer heartbeat.Stop()
+	for err == nil {
+		select {
+		case <-readDone:
+			conn.Close(websocket.CloseNormal, "")
+			return
+		case <-cs.streams.done:
+			conn.Close(websocket.CloseGoingAway, "server shutting down")
+			return
+		case s := <-updates:
+			if s != last {
+				last = s
+				err = sendRates(s)
+			}
+		case cmd := <-commands:
+			var subscribed bool
+			subscribed, err = cs.applyCommand(conn, sub, cmd)
+			if err == nil && subscribed {
+				err = sendRates(last)
+			}
+		case now := <-heartbeat.C:
+			if err = conn.WriteJSON(map[string]any{"type": "heartbeat", "time": now.UTC()}); err == nil {
+				err = conn.Ping()
+			}
+		}
+	}
+
+	if cs.slowConsumer(transportWebSocket, err) {
+		slog.WarnContext(r.Context(), "disconnected slow rate stream consumer", "transport", transportWebSocket)
+	} else if !errors.Is(err, net.ErrClosed) {
+		slog.DebugContext(r.Context(), "rate stream ended", "transport", transportWebSocket, "error", err)
+	}
+	conn.Close(websocket.CloseGoingAway, "")
+}
+
+// applyCommand updates the subscription and acknowledges the command, or
+// reports an error message to the client. It returns true when symbols
+// were subscribed, so their rates are due.
+func (cs *CurrencyService) applyCommand(conn *websocket.Conn, sub *wsSubscription, cmd wsCommand) (bool, error) {
+	if cmd.Action != "subscribe" && cmd.Action != "unsubscribe" {
+		return false, conn.WriteJSON(map[string]string{
+			"type": "error", "code": CodeInvalidParameter, "field": "action",
+			"error": `Unknown action, expected "subscribe" or "unsubscribe"`,
+		})
+	}
+	symbols, err := cs.parseSymbols(cmd.Symbols)
+	if err != nil {
+		_, code, field := Classify(err)
+		return false, conn.WriteJSON(map[string]string{"type": "error", "code": code, "field": field, "error": err.Error()})
+	}
+
+	switch {
+	case cmd.Action == "subscribe" && len(symbols) == 0:
+		sub.all, sub.symbols = true, map[string]bool{}
+	case cmd.Action == "subscribe":
+		for sym := range symbols {
+			sub.symbols[sym] = true
+		}
+	case len(symbols) == 0:
+		sub.all, sub.symbols = false, map[string]bool{}
+	default:
+		if sub.all {
+			sub.all = false
+			for code := range cs.ActiveSnapshot().Rates {
+				sub.symbols[code] = true
+			}
+		}
+		for sym := range symbols {
+			delete(sub.symbols, sym)
+		}
+	}
+	return cmd.Action == "subscribe", conn.WriteJSON(map[string]any{"type": "subscribed", "symbols": sub.list()})
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/router"
+)
+
+// streamServer serves the routes of a service with a short heartbeat
+func streamServer(t *testing.T, opts StreamOptions) (*CurrencyService, *httptest.Server) {
+	t.Helper()
+	cs := NewCurrencyService(WithAnomalyGuard(nil), WithStreamOptions(opts))
+	rt := router.New(WriteError)
+	cs.RegisterRoutes(rt, RouteOptions{})
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return cs, srv
+}
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	id, event, data string
+}
+
+func readEvent(t *testing.T, br *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Reading event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return ev
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.event = value
+		case "data":
+			ev.data = value
+		}
+	}
+}
+
+// nextRates skips heartbeats until the next rates event
+func nextRates(t *testing.T, br *bufio.Reader) (sseEvent, RatesEvent) {
+	t.Helper()
+	for {
+		ev := readEvent(t, br)
+		if ev.event == "heartbeat" {
+			continue
+		}
+		var rates RatesEvent
+		if ev.event != "rates" || json.Unmarshal([]byte(ev.data), &rates) != nil {
+			t.Fatalf("Expected a rates event, got %+v", ev)
+		}
+		return ev, rates
+	}
+}
+
+func TestRatesStream(t *testing.T) {
+	cs, srv := streamServer(t, StreamOptions{Heartbeat: 20 * time.Millisecond, WriteTimeout: time.Second, MaxClients: 1})
+
+	resp, err := http.Get(srv.URL + "/rates/stream?symbols=eur,GBP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, ct)
+	}
+	br := bufio.NewReader(resp.Body)
+
+	ev, rates := nextRates(t, br)
+	if ev.id != "1" || rates.Version != 1 || len(rates.Rates) != 2 || rates.Rates["EUR"] != ExchangeRates["EUR"] {
+		t.Errorf("Expected version 1 with EUR and GBP, got id %q %+v", ev.id, rates)
+	}
+
+	// A second client is over the limit
+	over, err := http.Get(srv.URL + "/rates/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	over.Body.Close()
+	if over.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 over the client limit, got %d", over.StatusCode)
+	}
+
+	if ev := readEvent(t, br); ev.event != "heartbeat" {
+		t.Errorf("Expected a heartbeat while idle, got %+v", ev)
+	}
+
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8, "JPY": 150})); err != nil {
+		t.Fatal(err)
+	}
+	ev, rates = nextRates(t, br)
+	if ev.id != "2" || rates.Rates["EUR"] != 0.9 || len(rates.Rates) != 2 {
+		t.Errorf("Expected pushed version 2 with EUR 0.9, got id %q %+v", ev.id, rates)
+	}
+
+	cs.StopStreams()
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Errorf("Expected the stream to end cleanly on shutdown, got %v", err)
+	}
+}
+
+func TestRatesStreamUnknownSymbol(t *testing.T) {
+	_, srv := streamServer(t, DefaultStreamOptions())
+	for _, path := range []string{"/rates/stream?symbols=EUR,XYZ", "/rates/ws?symbols=XYZ"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestRatesStreamRejectsHead(t *testing.T) {
+	cs, srv := streamServer(t, DefaultStreamOptions())
+	// Ends a stream a HEAD request opened, so the test fails instead of hanging
+	t.Cleanup(cs.StopStreams)
+	client := &http.Client{Timeout: 2 * time.Second}
+	for _, path := range []string{"/v1/rates/stream", "/v1/rates/ws", "/rates/stream", "/rates/ws"} {
+		resp, err := client.Head(srv.URL + path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET" {
+			t.Errorf("%s: expected 405 allowing GET, got %d %q", path, resp.StatusCode, resp.Header.Get("Allow"))
+		}
+	}
+}
+
+// wsClient is a minimal WebSocket client for the rate stream
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialRates(t *testing.T, srv *httptest.Server, query string) *wsClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/rates/ws"+query, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	return &wsClient{conn: conn, br: br}
+}
+
+// send writes a masked text frame
+func (c *wsClient) send(t *testing.T, msg string) {
+	t.Helper()
+	frame := []byte{0x81, 0x80 | byte(len(msg)), 0, 0, 0, 0}
+	if _, err := c.conn.Write(append(frame, msg...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// read returns the next frame, skipping pings
+func (c *wsClient) read(t *testing.T) (opcode byte, payload []byte) {
+	t.Helper()
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.br, head[:]); err != nil {
+			t.Fatalf("Reading frame: %v", err)
+		}
+		n := uint64(head[1] & 0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			io.ReadFull(c.br, ext[:])
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			io.ReadFull(c.br, ext[:])
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		payload = make([]byte, n)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			t.Fatalf("Reading frame: %v", err)
+		}
+		if opcode = head[0] & 0x0f; opcode != 0x9 {
+			return opcode, payload
+		}
+	}
+}
+
+// message returns the next JSON message that is not a heartbeat
+func (c *wsClient) message(t *testing.T) map[string]any {
+	t.Helper()
+	for {
+		opcode, payload := c.read(t)
+		if opcode != 0x1 {
+			t.Fatalf("Expected a text message, got opcode %d", opcode)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg["type"] != "heartbeat" {
+			return msg
+		}
+	}
+}
+
+func TestRatesWebSocket(t *testing.T) {
+	cs, srv := streamServer(t, StreamOptions{Heartbeat: 20 * time.Millisecond, WriteTimeout: time.Second})
+	client := dialRates(t, srv, "")
+
+	steps := []struct {
+		name     string
+		send     string
+		expected []string
+	}{
+		{"Subscribe", `{"action":"subscribe","symbols":["eur","GBP"]}`, []string{`subscribed ["EUR","GBP"]`, `rates {"EUR":0.85,"GBP":0.73}`}},
+		{"Unsubscribe one", `{"action":"unsubscribe","symbols":["GBP"]}`, []string{`subscribed ["EUR"]`}},
+		{"Unknown symbol", `{"action":"subscribe","symbols":["XYZ"]}`, []string{`error currency XYZ not supported`}},
+		{"Unknown action", `{"action":"pause"}`, []string{`error Unknown action, expected "subscribe" or "unsubscribe"`}},
+	}
+	for _, step := range steps {
+		client.send(t, step.send)
+		for _, expected := range step.expected {
+			if got := describe(client.message(t)); got != expected {
+				t.Errorf("%s: expected %s, got %s", step.name, expected, got)
+			}
+		}
+	}
+
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8})); err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(client.message(t)); got != `rates {"EUR":0.9}` {
+		t.Errorf("Expected pushed EUR rate, got %s", got)
+	}
+
+	cs.StopStreams()
+	opcode, payload := client.read(t)
+	if opcode != 0x8 || len(payload) < 2 || binary.BigEndian.Uint16(payload) != 1001 {
+		t.Errorf("Expected a going away close frame, got opcode %d %q", opcode, payload)
+	}
+}
+
+// describe summarises a WebSocket message as its type and main field
+func describe(msg map[string]any) string {
+	var detail any
+	switch msg["type"] {
+	case "subscribed":
+		detail = msg["symbols"]
+	case "rates":
+		detail = msg["rates"]
+	case "error":
+		return "error " + msg["error"].(string)
+	}
+	b, _ := json.Marshal(detail)
+	return msg["type"].(string) + " " + string(b)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"currency_go_microservice/internal/provider"
+)
+
+func TestServiceSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	cs := NewCurrencyService(WithRateProvider(provider.NewAggregator(0, provider.NewStaticProvider("feed", "USD", ExchangeRates))))
+	if err := cs.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cs.ExchangeHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/exchange?from=USD&to=XYZ&amount=1", nil))
+
+	parents := make(map[string]string)
+	byID := make(map[string]string)
+	failed := make(map[string]bool)
+	for _, s := range recorder.Ended() {
+		byID[s.SpanContext().SpanID().String()] = s.Name()
+		failed[s.Name()] = s.Status().Code.String() == "Error"
+	}
+	for _, s := range recorder.Ended() {
+		parents[s.Name()] = byID[s.Parent().SpanID().String()]
+	}
+
+	tests := []struct {
+		span   string
+		parent string
+	}{
+		{"RateProvider.FetchRates", "Refresh"},
+		{"SnapshotStore.Save", "Refresh"},
+		{"ConvertCurrency", ""},
+	}
+	for _, tt := range tests {
+		parent, ok := parents[tt.span]
+		if !ok {
+			t.Errorf("Expected a %s span", tt.span)
+			continue
+		}
+		if parent != tt.parent {
+			t.Errorf("Expected %s to be a child of %q, got %q", tt.span, tt.parent, parent)
+		}
+	}
+	if !failed["ConvertCurrency"] {
+		t.Errorf("Expected unsupported currency to mark the ConvertCurrency span as failed")
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"net/http"
+	"time"
+)
+
+// Version 2 of the API reports amounts and rates as decimal strings, so
+// clients can parse them into decimal types without a detour through
+// binary floating point. Snapshot details are grouped under "snapshot" and
+// warnings are a list. It is offered as JSON and MessagePack.
+
+// negotiateV2 chooses among the formats the v2 routes offer
+var negotiateV2 = negotiator(offer("json", "msgpack"))
+
+// SnapshotRef identifies the rate snapshot a v2 response was computed from
+type SnapshotRef struct {
+	Version uint64    `json:"version"`
+	AsOf    time.Time `json:"as_of"`
+	Source  string    `json:"source"`
+	Stale   bool      `json:"stale"`
+}
+
+// Money is a decimal amount in a currency
+type Money struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+// ExchangeResponseV2 is a conversion in the v2 format
+type ExchangeResponseV2 struct {
+	From     Money       `json:"from"`
+	To       Money       `json:"to"`
+	Rate     string      `json:"rate"`
+	Snapshot SnapshotRef `json:"snapshot"`
+	Warnings []string    `json:"warnings"`
+}
+
+// warnings lists a warning, never returning nil so it encodes as []
+func warnings(warning string) []string {
+	if warning == "" {
+		return []string{}
+	}
+	return []string{warning}
+}
+
+func (e *ExchangeResponse) v2() *ExchangeResponseV2 {
+	return &ExchangeResponseV2{
+		From:     Money{Currency: e.From, Amount: formatFloat(e.Amount)},
+		To:       Money{Currency: e.To, Amount: formatFloat(e.ConvertedAmount)},
+		Rate:     formatFloat(e.Rate),
+		Snapshot: SnapshotRef{Version: e.Version, AsOf: e.AsOf, Source: e.Source, Stale: e.Warning != ""},
+		Warnings: warnings(e.Warning),
+	}
+}
+
+// BatchResponseV2 is a batch in the v2 format, with a status per entry
+type BatchResponseV2 struct {
+	Results   []BatchResultV2 `json:"results"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+}
+
+// BatchResultV2 is one batch entry. Status is "ok" with a Conversion or
+// "error" with an Error.
+type BatchResultV2 struct {
+	Status     string              `json:"status"`
+	Conversion *ExchangeResponseV2 `json:"conversion,omitempty"`
+	Error      *BatchError         `json:"error,omitempty"`
+}
+
+func (b *BatchResponse) v2() *BatchResponseV2 {
+	out := &BatchResponseV2{Results: make([]BatchResultV2, len(b.Results))}
+	for i, result := range b.Results {
+		if result.Error != nil {
+			out.Results[i] = BatchResultV2{Status: "error", Error: result.Error}
+			out.Failed++
+			continue
+		}
+		out.Results[i] = BatchResultV2{Status: "ok", Conversion: result.Conversion.v2()}
+		out.Succeeded++
+	}
+	return out
+}
+
+// RatesResponseV2 is the rate table in the v2 format, sorted by currency
+type RatesResponseV2 struct {
+	Base     string      `json:"base"`
+	Rates    []RateV2    `json:"rates"`
+	Snapshot SnapshotRef `json:"snapshot"`
+	Warnings []string    `json:"warnings"`
+}
+
+// RateV2 is the rate of one currency against the base
+type RateV2 struct {
+	Currency string `json:"currency"`
+	Rate     string `json:"rate"`
+}
+
+func (r *RatesResponse) v2() *RatesResponseV2 {
+	out := &RatesResponseV2{
+		Base:     r.Base,
+		Rates:    make([]RateV2, 0, len(r.Rates)),
+		Snapshot: SnapshotRef{Version: r.Version, AsOf: r.AsOf, Source: r.Source, Stale: r.Warning != ""},
+		Warnings: warnings(r.Warning),
+	}
+	for _, code := range r.Rates.codes() {
+		out.Rates = append(out.Rates, RateV2{Currency: code, Rate: formatFloat(r.Rates[code])})
+	}
+	return out
+}
+
+// ExchangeV2Handler is ExchangeHandler with a v2 response
+func (cs *CurrencyService) ExchangeV2Handler(w http.ResponseWriter, r *http.Request) {
+	if response, ok := cs.exchangeQuery(w, r); ok {
+		writeResponse(w, r, response.v2())
+	}
+}
+
+// ExchangeBatchV2Handler is ExchangeBatchHandler with a v2 response
+func (cs *CurrencyService) ExchangeBatchV2Handler(w http.ResponseWriter, r *http.Request) {
+	if response, ok := cs.exchangeBody(w, r); ok {
+		writeResponse(w, r, response.v2())
+	}
+}
+
+// RatesV2Handler is RatesHandler with a v2 response
+func (cs *CurrencyService) RatesV2Handler(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, cs.ratesResponse(cs.ActiveSnapshot()).v2())
+}
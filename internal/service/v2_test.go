@@ -0,0 +1,156 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/router"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	legacy := LegacyRoutes{
+		DeprecatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC),
+	}
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{SnapshotAdmin: true, Legacy: legacy})
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		deprecated     bool
+		successor      string
+	}{
+		{"v1 exchange", "GET", "/v1/exchange?from=USD&to=EUR&amount=1", http.StatusOK, false, ""},
+		{"Legacy exchange", "GET", "/exchange?from=USD&to=EUR&amount=1", http.StatusOK, true, "</v1/exchange>"},
+		{"Legacy error", "GET", "/exchange", http.StatusBadRequest, true, "</v1/exchange>"},
+		{"Legacy admin", "POST", "/snapshots/1/activate", http.StatusOK, true, "</v1/snapshots/1/activate>"},
+		{"v1 rates", "GET", "/v1/rates", http.StatusOK, false, ""},
+		{"v2 rates", "GET", "/v2/rates", http.StatusOK, false, ""},
+		{"Probes are unversioned", "GET", "/livez", http.StatusOK, false, ""},
+		{"No v1 probes", "GET", "/v1/livez", http.StatusNotFound, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			h := rr.Header()
+			if !tt.deprecated {
+				if h.Get("Deprecation") != "" || h.Get("Sunset") != "" {
+					t.Errorf("Expected no deprecation headers, got %v", h)
+				}
+				return
+			}
+			if h.Get("Deprecation") != "@1790812800" || h.Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" {
+				t.Errorf("Unexpected deprecation headers: Deprecation %q, Sunset %q", h.Get("Deprecation"), h.Get("Sunset"))
+			}
+			if link := h.Get("Link"); link != tt.successor+`; rel="successor-version"` {
+				t.Errorf("Expected successor link %s, got %q", tt.successor, link)
+			}
+		})
+	}
+}
+
+func TestLegacyRoutesOptions(t *testing.T) {
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{})
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, httptest.NewRequest("GET", "/rates", nil))
+	if rr.Header().Get("Deprecation") != "true" || rr.Header().Get("Sunset") != "" {
+		t.Errorf("Expected an undated deprecation without sunset, got %v", rr.Header())
+	}
+
+	rt = router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{Legacy: LegacyRoutes{Disabled: true}})
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, httptest.NewRequest("GET", "/rates", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected disabled legacy routes to be gone, got %d", rr.Code)
+	}
+}
+
+func TestV2Responses(t *testing.T) {
+	rt := router.New(WriteError)
+	NewCurrencyService().RegisterRoutes(rt, RouteOptions{})
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		expected string
+	}{
+		{"Exchange", "GET", "/v2/exchange?from=usd&to=gbp&amount=10", "",
+			`{"from":{"currency":"USD","amount":"10"},"to":{"currency":"GBP","amount":"7.3"},"rate":"0.73","snapshot":{"version":1,"source":"builtin","stale":false},"warnings":[]}`},
+		{"Batch", "POST", "/v2/exchange/batch", `{"conversions":[{"from":"USD","to":"EUR","amount":2},{"from":"USD","to":"XYZ","amount":1}]}`,
+			`{"results":[{"status":"ok","conversion":{"from":{"currency":"USD","amount":"2"},"to":{"currency":"EUR","amount":"1.7"},"rate":"0.85","snapshot":{"version":1,"source":"builtin","stale":false},"warnings":[]}},` +
+				`{"status":"error","error":{"code":"UNSUPPORTED_CURRENCY","field":"to","message":"currency XYZ not supported"}}],"succeeded":1,"failed":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			// as_of is the seed time, so compare without it
+			var got any
+			json.Unmarshal(rr.Body.Bytes(), &got)
+			stripAsOf(got)
+			b, _ := json.Marshal(got)
+			var expected any
+			json.Unmarshal([]byte(tt.expected), &expected)
+			e, _ := json.Marshal(expected)
+			if string(b) != string(e) {
+				t.Errorf("Expected %s, got %s", e, b)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, httptest.NewRequest("GET", "/v2/rates", nil))
+	var rates RatesResponseV2
+	if err := json.Unmarshal(rr.Body.Bytes(), &rates); err != nil {
+		t.Fatal(err)
+	}
+	if len(rates.Rates) != len(ExchangeRates) || rates.Rates[0] != (RateV2{Currency: "AUD", Rate: "1.35"}) || rates.Snapshot.Version != 1 {
+		t.Errorf("Expected a sorted rate list, got %+v", rates)
+	}
+
+	// v2 is only offered as JSON and MessagePack
+	for accept, status := range map[string]int{"application/msgpack": http.StatusOK, "text/csv": http.StatusNotAcceptable} {
+		req := httptest.NewRequest("GET", "/v2/rates", nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		if rr.Code != status {
+			t.Errorf("Accept %s: expected %d, got %d", accept, status, rr.Code)
+		}
+	}
+}
+
+// stripAsOf removes as_of members from decoded JSON
+func stripAsOf(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		delete(v, "as_of")
+		for _, child := range v {
+			stripAsOf(child)
+		}
+	case []any:
+		for _, child := range v {
+			stripAsOf(child)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import "sync"
+
+// watchers fans snapshot changes out to subscribers
+type watchers struct {
+	mu   sync.Mutex
+	subs map[chan *RateSnapshot]struct{}
+}
+
+// Subscribe returns a channel that receives every snapshot that becomes
+// active, whether installed or rolled back to, and a function ending the
+// subscription. The channel holds only the newest undelivered snapshot,
+// so a slow subscriber skips intermediate versions instead of holding up
+// installs. The channel is closed when the subscription ends.
+func (cs *CurrencyService) Subscribe() (<-chan *RateSnapshot, func()) {
+	ch := make(chan *RateSnapshot, 1)
+	w := &cs.watchers
+	w.mu.Lock()
+	if w.subs == nil {
+		w.subs = make(map[chan *RateSnapshot]struct{})
+	}
+	w.subs[ch] = struct{}{}
+	w.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			w.mu.Lock()
+			delete(w.subs, ch)
+			w.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publish hands s to every subscriber, replacing any snapshot the
+// subscriber has not received yet
+func (w *watchers) publish(s *RateSnapshot) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.subs {
+		for sent := false; !sent; {
+			select {
+			case ch <- s:
+				sent = true
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+			}
+		}
+	}
+}
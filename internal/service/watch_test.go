@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSubscribeDeliversLatestSnapshot(t *testing.T) {
+	cs := NewCurrencyService(WithAnomalyGuard(nil))
+	updates, cancel := cs.Subscribe()
+
+	// Two installs without a receive: only the newer one is kept
+	for _, eur := range []float64{0.86, 0.87} {
+		rates := map[string]float64{"USD": 1, "EUR": eur, "GBP": 0.73}
+		if err := cs.InstallSnapshot(NewRateSnapshot("USD", rates)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	got := <-updates
+	if got.Version != 3 || got.Rates["EUR"] != 0.87 {
+		t.Errorf("Expected version 3 with EUR 0.87, got version %d with %v", got.Version, got.Rates["EUR"])
+	}
+
+	if _, err := cs.ActivateSnapshot(context.Background(), 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := <-updates; got.Version != 2 {
+		t.Errorf("Expected rollback to version 2 to be published, got %d", got.Version)
+	}
+
+	cancel()
+	cancel()
+	if _, open := <-updates; open {
+		t.Errorf("Expected channel to be closed after cancel")
+	}
+	// Installing after cancel must not block or panic
+	if err := cs.InstallSnapshot(NewRateSnapshot("USD", ExchangeRates)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestExchangeErrors(t *testing.T) {
+	cs := NewCurrencyService()
+
+	tests := []struct {
+		name     string
+		from, to string
+		amount   float64
+		check    func(error) bool
+	}{
+		{"Unsupported source", "XYZ", "EUR", 10, func(err error) bool {
+			var e *UnsupportedCurrencyError
+			return errors.As(err, &e) && e.Currency == "XYZ"
+		}},
+		{"Unsupported target", "USD", "abc", 10, func(err error) bool {
+			var e *UnsupportedCurrencyError
+			return errors.As(err, &e) && e.Error() == "currency abc not supported"
+		}},
+		{"Zero amount", "USD", "EUR", 0, func(err error) bool { return errors.Is(err, ErrInvalidAmount) }},
+		{"Negative amount", "USD", "EUR", -5, func(err error) bool { return errors.Is(err, ErrInvalidAmount) }},
+		{"Missing target", "USD", "", 10, func(err error) bool {
+			var e *RequestError
+			return errors.As(err, &e) && e.Code == CodeMissingParameter && e.Field == "to"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cs.Exchange(context.Background(), tt.from, tt.to, tt.amount)
+			if !tt.check(err) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	results, err := cs.ExchangeBatch(context.Background(), []ExchangeRequest{
+		{From: "USD", To: "EUR", Amount: 100},
+		{From: "USD", To: "XYZ", Amount: 100},
+		{To: "EUR", Amount: 100},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if results[0].Err != nil || results[0].Response.ConvertedAmount != 85 {
+		t.Errorf("Expected first conversion to succeed, got %+v", results[0])
+	}
+	if results[1].Err == nil || results[1].Response != nil {
+		t.Errorf("Expected second conversion to fail on its own, got %+v", results[1])
+	}
+	if _, code, field := Classify(results[2].Err); code != CodeMissingParameter || field != "from" {
+		t.Errorf("Expected MISSING_PARAMETER on from, got %+v", results[2])
+	}
+
+	for _, reqs := range [][]ExchangeRequest{nil, make([]ExchangeRequest, MaxBatchSize+1)} {
+		var e *RequestError
+		if _, err := cs.ExchangeBatch(context.Background(), reqs); !errors.As(err, &e) || e.Field != "conversions" {
+			t.Errorf("Expected a batch of %d to fail as a whole, got %v", len(reqs), err)
+		}
+	}
+}
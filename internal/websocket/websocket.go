@@ -0,0 +1,326 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is appended to the client key to form Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+)
+
+// DefaultMaxMessageSize bounds incoming messages unless Conn.MaxMessageSize
+// is set
+const DefaultMaxMessageSize = 64 << 10
+
+// HandshakeError is returned by Upgrade when the request is not a valid
+// WebSocket handshake. Nothing has been written to the response, so the
+// caller reports Status and Message in its own error format.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string { return e.Message }
+
+// CloseError is returned by ReadMessage once the peer closed the connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed with code %d %s", e.Code, e.Reason)
+}
+
+// Conn is a server-side WebSocket connection. Reads must come from one
+// goroutine; writes may come from several.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	// MaxMessageSize bounds incoming messages; larger ones close the
+	// connection with CloseMessageTooBig
+	MaxMessageSize int64
+	// IdleTimeout, when set, closes the connection if no frame arrives for
+	// that long. Pings sent by the server are answered with pongs, so
+	// pinging more often keeps live clients connected.
+	IdleTimeout time.Duration
+	// WriteTimeout, when set, bounds every write, so a client that stops
+	// reading cannot stall its writer
+	WriteTimeout time.Duration
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Upgrade completes the WebSocket handshake and takes over the connection.
+// checkOrigin decides on cross-origin requests; nil only allows requests
+// without an Origin header or from the same host.
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(origin string) bool) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, &HandshakeError{http.StatusMethodNotAllowed, "WebSocket handshake must use GET"}
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, &HandshakeError{http.StatusUpgradeRequired, "Expected a WebSocket upgrade request"}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, &HandshakeError{http.StatusUpgradeRequired, "Unsupported WebSocket version"}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, &HandshakeError{http.StatusBadRequest, "Invalid Sec-WebSocket-Key header"}
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !sameHost(origin, r.Host) && (checkOrigin == nil || !checkOrigin(origin)) {
+		return nil, &HandshakeError{http.StatusForbidden, "Origin not allowed"}
+	}
+
+	// Writing the 101 through the ResponseWriter lets logging and metrics
+	// middleware record it; Hijack then flushes it to the client
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(key))
+	w.WriteHeader(http.StatusSwitchingProtocols)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// Clear deadlines inherited from the HTTP server's timeouts
+	netConn.SetDeadline(time.Time{})
+	return &Conn{conn: netConn, br: brw.Reader, MaxMessageSize: DefaultMaxMessageSize}, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameHost(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// reassembling fragments on the way. It returns a *CloseError once the
+// peer closes the connection.
+func (c *Conn) ReadMessage() (text bool, data []byte, err error) {
+	var message []byte
+	var opcode byte
+	for {
+		if c.IdleTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.IdleTimeout))
+		}
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return false, nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return false, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &CloseError{Code: CloseNormal}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.Close(CloseNormal, "")
+			return false, nil, closeErr
+		case opText, opBinary:
+			if opcode != 0 {
+				return false, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			opcode = op
+		case opContinuation:
+			if opcode == 0 {
+				return false, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return false, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if int64(len(message)+len(payload)) > c.MaxMessageSize {
+			return false, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode == opText, message, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	length := int64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if opcode >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length < 0 || length > c.MaxMessageSize {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteText sends data as one text message
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// WriteJSON sends v encoded as JSON in a text message
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteText(data)
+}
+
+// Ping sends a ping; the client answers with a pong
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// writeFrame writes a single unmasked frame, as servers must
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	// A close frame is a courtesy and must not hold up closing
+	timeout := c.WriteTimeout
+	if opcode == opClose && (timeout <= 0 || timeout > time.Second) {
+		timeout = time.Second
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	_, err := (&net.Buffers{header, payload}).WriteTo(c.conn)
+	return err
+}
+
+// fail closes the connection with code after a protocol violation
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// Close sends a close frame with code and reason, best effort, and closes
+// the connection. Further calls do nothing.
+func (c *Conn) Close(code int, reason string) error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+		if len(payload) > 125 {
+			payload = payload[:125]
+		}
+		c.writeFrame(opClose, payload)
+		err = c.conn.Close()
+	})
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
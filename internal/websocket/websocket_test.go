@@ -0,0 +1,227 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient speaks the client side of the protocol over a raw connection
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dial performs the handshake against srv and returns the raw response
+func dial(t *testing.T, srv *httptest.Server, header http.Header) (*testClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest("GET", srv.URL+"/ws", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testClient{conn: conn, br: br}, resp
+}
+
+func (c *testClient) write(t *testing.T, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0, 0x80 | byte(len(payload))}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, p := range payload {
+		frame = append(frame, p^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatalf("Server frames must not be masked")
+	}
+	n := int(header[1] & 0x7F)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestHandshake(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, func(origin string) bool { return origin == "https://shop.example.com" })
+		var he *HandshakeError
+		if errors.As(err, &he) {
+			http.Error(w, he.Message, he.Status)
+			return
+		}
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		conn.Close(CloseNormal, "")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{"Valid handshake", nil, http.StatusSwitchingProtocols},
+		{"Same host origin", http.Header{"Origin": {srv.URL}}, http.StatusSwitchingProtocols},
+		{"Allowed origin", http.Header{"Origin": {"https://shop.example.com"}}, http.StatusSwitchingProtocols},
+		{"Foreign origin", http.Header{"Origin": {"https://evil.example.net"}}, http.StatusForbidden},
+		{"Old version", http.Header{"Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired},
+		{"Bad key", http.Header{"Sec-Websocket-Key": {"short"}}, http.StatusBadRequest},
+		{"Plain request", http.Header{"Upgrade": {"h2c"}}, http.StatusUpgradeRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := dial(t, srv, tt.header)
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if tt.expectedStatus == http.StatusSwitchingProtocols {
+				// Example key and accept value from RFC 6455 section 1.3
+				if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+					t.Errorf("Expected RFC 6455 accept value, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	received := make(chan string, 4)
+	closed := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		conn.MaxMessageSize = 64
+		for {
+			text, data, err := conn.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			if !text {
+				t.Errorf("Expected text message")
+			}
+			received <- string(data)
+			conn.WriteJSON(map[string]string{"echo": string(data)})
+		}
+	}))
+	defer srv.Close()
+	client, _ := dial(t, srv, nil)
+
+	// A fragmented message with a ping in between
+	client.write(t, false, opText, []byte("hel"))
+	client.write(t, true, opPing, []byte("are you there"))
+	client.write(t, true, opContinuation, []byte("lo"))
+
+	if op, payload := client.read(t); op != opPong || string(payload) != "are you there" {
+		t.Errorf("Expected pong echoing the ping, got %x %q", op, payload)
+	}
+	if got := <-received; got != "hello" {
+		t.Errorf("Expected reassembled message, got %q", got)
+	}
+	if op, payload := client.read(t); op != opText || !bytes.Equal(payload, []byte(`{"echo":"hello"}`)) {
+		t.Errorf("Expected JSON echo, got %x %q", op, payload)
+	}
+
+	client.write(t, true, opText, []byte(strings.Repeat("x", 100)))
+	if op, payload := client.read(t); op != opClose || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Errorf("Expected close with 1009, got %x %v", op, payload)
+	}
+	var closeErr *CloseError
+	if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Errorf("Expected CloseError 1009, got %v", err)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	closed := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		closed <- err
+	}))
+	defer srv.Close()
+	client, _ := dial(t, srv, nil)
+
+	client.write(t, true, opClose, append(binary.BigEndian.AppendUint16(nil, CloseGoingAway), "bye"...))
+	if op, _ := client.read(t); op != opClose {
+		t.Errorf("Expected close frame in reply, got %x", op)
+	}
+	var closeErr *CloseError
+	if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Reason != "bye" {
+		t.Errorf("Expected CloseError 1001 bye, got %v", err)
+	}
+}
+
+func TestUnmaskedFrameIsRejected(t *testing.T) {
+	closed := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _ := Upgrade(w, r, nil)
+		_, _, err := conn.ReadMessage()
+		closed <- err
+	}))
+	defer srv.Close()
+	client, _ := dial(t, srv, nil)
+
+	client.conn.Write([]byte{0x81, 0x02, 'h', 'i'})
+	var closeErr *CloseError
+	if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != CloseProtocolError {
+		t.Errorf("Expected protocol error, got %v", err)
+	}
+}
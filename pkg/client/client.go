@@ -0,0 +1,340 @@
+// Package client is a Go client for the currency exchange service's HTTP
+// API. It calls the /v1 endpoints, retries failed calls with jittered
+// exponential backoff and reports error responses as *Error values whose
+// codes can be matched with errors.Is:
+//
+//	c, err := client.New("https://rates.example.com", client.WithAPIKey(key))
+//	...
+//	conversion, err := c.Convert(ctx, "USD", "EUR", 100)
+//	if errors.Is(err, client.CodeUnsupportedCurrency) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the service. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	token      string
+	userAgent  string
+	retry      RetryPolicy
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of http.DefaultClient
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAPIKey authenticates requests with an API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBearerToken authenticates requests with a JWT access token
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithUserAgent replaces the User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetry replaces DefaultRetryPolicy
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// RetryPolicy controls how calls are retried after network errors, 5xx
+// responses and 429 Too Many Requests. Attempt n waits a random duration
+// up to BaseDelay*2^(n-1), capped at MaxDelay, unless the response says
+// how long to wait with Retry-After. A Retry-After longer than MaxDelay
+// is not waited for; the error is returned instead.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt; 1 disables retries
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy makes up to three attempts within about two seconds
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+}
+
+// backoff is the jittered delay before retry attempt n, counting from 1
+func (p RetryPolicy) backoff(n int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := n - 1; shift < 32 && p.BaseDelay<<shift > 0 && p.BaseDelay<<shift < ceiling {
+		ceiling = p.BaseDelay << shift
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// New creates a client for the service at baseURL, e.g.
+// "https://rates.example.com"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "currency-go-client",
+		retry:      DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c, nil
+}
+
+// Conversion is the result of converting an amount
+type Conversion struct {
+	From            string    `json:"from"`
+	To              string    `json:"to"`
+	Amount          float64   `json:"amount"`
+	ConvertedAmount float64   `json:"converted_amount"`
+	Rate            float64   `json:"rate"`
+	Version         uint64    `json:"version"`
+	AsOf            time.Time `json:"as_of"`
+	Source          string    `json:"source"`
+	// Warning is set when the rates are stale
+	Warning string `json:"warning,omitempty"`
+}
+
+// ConversionRequest is one entry of a batch
+type ConversionRequest struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+// BatchResult is the outcome of one batch entry. Exactly one of
+// Conversion and Err is set.
+type BatchResult struct {
+	Conversion *Conversion
+	Err        *Error
+}
+
+// Rates is the active rate table
+type Rates struct {
+	Base    string             `json:"base"`
+	Rates   map[string]float64 `json:"rates"`
+	Version uint64             `json:"version"`
+	AsOf    time.Time          `json:"as_of"`
+	Source  string             `json:"source"`
+	Warning string             `json:"warning,omitempty"`
+}
+
+// Snapshot is an installed rate snapshot
+type Snapshot struct {
+	Version     uint64    `json:"version"`
+	Base        string    `json:"base"`
+	Currencies  int       `json:"currencies"`
+	InstalledAt time.Time `json:"installed_at"`
+	Active      bool      `json:"active"`
+}
+
+// Convert converts amount from one currency to another
+func (c *Client) Convert(ctx context.Context, from, to string, amount float64) (*Conversion, error) {
+	query := url.Values{
+		"from":   {from},
+		"to":     {to},
+		"amount": {strconv.FormatFloat(amount, 'f', -1, 64)},
+	}
+	var conversion Conversion
+	if err := c.do(ctx, http.MethodGet, "/v1/exchange", query, nil, &conversion); err != nil {
+		return nil, err
+	}
+	return &conversion, nil
+}
+
+// BatchConvert converts up to 100 amounts against one rate snapshot. The
+// results are in request order and fail on their own; err is only set
+// when the batch as a whole fails.
+func (c *Client) BatchConvert(ctx context.Context, conversions []ConversionRequest) ([]BatchResult, error) {
+	var response struct {
+		Results []struct {
+			Conversion *Conversion `json:"conversion"`
+			Error      *struct {
+				Code    Code   `json:"code"`
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"results"`
+	}
+	body := map[string][]ConversionRequest{"conversions": conversions}
+	if err := c.do(ctx, http.MethodPost, "/v1/exchange/batch", nil, body, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(response.Results))
+	for i, result := range response.Results {
+		results[i].Conversion = result.Conversion
+		if e := result.Error; e != nil {
+			results[i].Err = &Error{Code: e.Code, Field: e.Field, Detail: e.Message}
+		}
+	}
+	return results, nil
+}
+
+// Rates returns the active rate table
+func (c *Client) Rates(ctx context.Context) (*Rates, error) {
+	var rates Rates
+	if err := c.do(ctx, http.MethodGet, "/v1/rates", nil, nil, &rates); err != nil {
+		return nil, err
+	}
+	return &rates, nil
+}
+
+// Currencies returns the supported currency codes, sorted
+func (c *Client) Currencies(ctx context.Context) ([]string, error) {
+	rates, err := c.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(rates.Rates))
+	for code := range rates.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// History lists the installed rate snapshots, oldest first. It requires
+// the admin scope and the snapshot admin endpoints to be enabled.
+func (c *Client) History(ctx context.Context) ([]Snapshot, error) {
+	var response struct {
+		Snapshots []Snapshot `json:"snapshots"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/snapshots", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Snapshots, nil
+}
+
+// do sends a request, retrying as the policy allows, and decodes a
+// successful JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("client: encoding request: %w", err)
+		}
+	}
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, method, u.String(), body, out)
+		if err == nil || attempt == c.retry.MaxAttempts {
+			return err
+		}
+		delay, ok := c.retryDelay(err, attempt)
+		if !ok {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay reports whether err is worth retrying and how long to wait
+// before retry attempt n
+func (c *Client) retryDelay(err error, n int) (time.Duration, bool) {
+	var e *Error
+	if !errors.As(err, &e) {
+		// Transport errors, unless the caller gave up
+		var urlErr *url.Error
+		return c.retry.backoff(n), errors.As(err, &urlErr) &&
+			!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	if e.Status != http.StatusTooManyRequests && e.Status < http.StatusInternalServerError {
+		return 0, false
+	}
+	if e.RetryAfter > 0 {
+		return e.RetryAfter, e.RetryAfter <= c.retry.MaxDelay
+	}
+	return c.retry.backoff(n), true
+}
+
+// send makes one attempt
+func (c *Client) send(ctx context.Context, method, target string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding %s response: %w", req.URL.Path, err)
+	}
+	return nil
+}
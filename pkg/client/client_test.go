@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/router"
+	"currency_go_microservice/internal/service"
+)
+
+// fastRetry keeps retry tests quick
+var fastRetry = WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+// newServiceClient returns a client of the real service routes
+func newServiceClient(t *testing.T) *Client {
+	t.Helper()
+	rt := router.New(service.WriteError)
+	service.NewCurrencyService().RegisterRoutes(rt, service.RouteOptions{SnapshotAdmin: true})
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL+"/", WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientAgainstService(t *testing.T) {
+	c := newServiceClient(t)
+	ctx := context.Background()
+
+	conversion, err := c.Convert(ctx, "USD", "EUR", 100)
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	if conversion.ConvertedAmount != 85 || conversion.Rate != 0.85 || conversion.Version != 1 || conversion.AsOf.IsZero() {
+		t.Errorf("Unexpected conversion %+v", conversion)
+	}
+
+	results, err := c.BatchConvert(ctx, []ConversionRequest{{From: "USD", To: "GBP", Amount: 10}, {From: "USD", To: "XYZ", Amount: 1}})
+	if err != nil {
+		t.Fatalf("BatchConvert: %v", err)
+	}
+	if len(results) != 2 || results[0].Conversion == nil || results[0].Conversion.ConvertedAmount != 7.3 || results[0].Err != nil {
+		t.Fatalf("Unexpected first batch result %+v", results)
+	}
+	if err := results[1].Err; results[1].Conversion != nil || !errors.Is(err, CodeUnsupportedCurrency) || err.Field != "to" {
+		t.Errorf("Expected an UNSUPPORTED_CURRENCY entry on to, got %+v", results[1])
+	}
+
+	rates, err := c.Rates(ctx)
+	if err != nil {
+		t.Fatalf("Rates: %v", err)
+	}
+	if rates.Base != "USD" || rates.Rates["JPY"] != 110 {
+		t.Errorf("Unexpected rates %+v", rates)
+	}
+
+	currencies, err := c.Currencies(ctx)
+	if err != nil {
+		t.Fatalf("Currencies: %v", err)
+	}
+	if len(currencies) != len(service.ExchangeRates) || !slices.IsSorted(currencies) || !slices.Contains(currencies, "BRL") {
+		t.Errorf("Unexpected currencies %v", currencies)
+	}
+
+	history, err := c.History(ctx)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 1 || history[0].Version != 1 || !history[0].Active || history[0].Currencies != len(service.ExchangeRates) {
+		t.Errorf("Unexpected history %+v", history)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	c := newServiceClient(t)
+
+	tests := []struct {
+		name   string
+		call   func() error
+		status int
+		code   Code
+		field  string
+	}{
+		{"Unsupported currency", func() error { _, err := c.Convert(context.Background(), "USD", "XYZ", 1); return err },
+			http.StatusBadRequest, CodeUnsupportedCurrency, "to"},
+		{"Invalid amount", func() error { _, err := c.Convert(context.Background(), "USD", "EUR", -5); return err },
+			http.StatusBadRequest, CodeInvalidAmount, "amount"},
+		{"Missing currency", func() error { _, err := c.Convert(context.Background(), "", "EUR", 1); return err },
+			http.StatusBadRequest, CodeMissingParameter, "from"},
+		{"Empty batch", func() error { _, err := c.BatchConvert(context.Background(), nil); return err },
+			http.StatusBadRequest, CodeMissingParameter, "conversions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Expected *Error, got %v", err)
+			}
+			if e.Status != tt.status || e.Code != tt.code || e.Field != tt.field || e.Detail == "" {
+				t.Errorf("Expected %d %s on %q, got %+v", tt.status, tt.code, tt.field, e)
+			}
+			if !errors.Is(err, tt.code) || errors.Is(err, CodeInternal) {
+				t.Errorf("Expected errors.Is to match only %s", tt.code)
+			}
+		})
+	}
+}
+
+func TestCodesMatchService(t *testing.T) {
+	codes := []Code{
+		CodeInvalidRequest, CodeInvalidBody, CodeMissingParameter, CodeInvalidParameter,
+		CodeInvalidAmount, CodeUnsupportedCurrency, CodeBatchTooLarge, CodeUnauthorized,
+		CodeForbidden, CodeNotFound, CodeSnapshotNotFound, CodeMethodNotAllowed,
+		CodeNotAcceptable, CodeUpgradeRequired, CodeRateLimited, CodeInternal,
+		CodeRatesStale, CodeUnavailable,
+	}
+	doc := service.OpenAPI(service.RouteOptions{})
+	documented := doc.Components.Schemas["ErrorResponse"].Properties["code"].Enum
+	if len(codes) != len(documented) {
+		t.Errorf("Expected %d codes, the service documents %d", len(codes), len(documented))
+	}
+	for _, code := range documented {
+		if !slices.Contains(codes, Code(code)) {
+			t.Errorf("Service code %s has no client constant", code)
+		}
+	}
+}
+
+// scripted serves the statuses in order, the last one repeatedly, and
+// counts the requests
+func scripted(t *testing.T, header http.Header, statuses ...int) (*Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		if status != http.StatusOK {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"title":%q,"status":%d,"detail":"attempt %d","code":"SCRIPTED"}`, http.StatusText(status), status, n)
+			return
+		}
+		fmt.Fprint(w, `{"base":"USD","rates":{"USD":1,"EUR":0.85},"version":3}`)
+	}))
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, &requests
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		statuses []int
+		attempts int32
+		status   int
+	}{
+		{"Recovers from 5xx", nil, []int{503, 502, 200}, 3, 0},
+		{"Recovers from 429", http.Header{"Retry-After": {"0"}}, []int{429, 200}, 2, 0},
+		{"Gives up after max attempts", nil, []int{500}, 3, 500},
+		{"Client errors are not retried", nil, []int{400, 200}, 1, 400},
+		{"Long Retry-After is not waited for", http.Header{"Retry-After": {"3600"}}, []int{429, 200}, 1, 429},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, requests := scripted(t, tt.header, tt.statuses...)
+			rates, err := c.Rates(context.Background())
+			if got := requests.Load(); got != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, got)
+			}
+			if tt.status == 0 {
+				if err != nil || rates.Version != 3 {
+					t.Errorf("Expected rates, got %+v, %v", rates, err)
+				}
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Status != tt.status || e.Detail != fmt.Sprintf("attempt %d", tt.attempts) {
+				t.Errorf("Expected the last %d response, got %v", tt.status, err)
+			}
+		})
+	}
+}
+
+func TestRetriesTransportErrors(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// Drop the connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"base":"USD","rates":{"USD":1}}`)
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL, fastRetry)
+
+	if _, err := c.Rates(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("Expected a successful retry, got %v after %d attempts", err, requests.Load())
+	}
+}
+
+func TestRetryHonoursContext(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// A fixed delay, as jittered backoff can be short enough to retry
+		// before the deadline
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL, WithRetry(RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Rates(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the backoff to stop with the context, took %v", elapsed)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 attempt before the deadline, got %d", n)
+	}
+}
+
+func TestNonServiceError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream connect error", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL, WithRetry(RetryPolicy{MaxAttempts: 1}))
+
+	_, err := c.Convert(context.Background(), "USD", "EUR", 1)
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadGateway || e.Code != "" || e.Detail != "upstream connect error" {
+		t.Errorf("Expected a 502 without a code, got %+v", err)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		fmt.Fprint(w, `{"base":"USD","rates":{}}`)
+	}))
+	defer srv.Close()
+
+	c, _ := New(srv.URL, WithAPIKey("cx_test"), WithBearerToken("token"), WithUserAgent("billing/1.2"))
+	if _, err := c.Rates(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := <-headers
+	if h.Get("X-API-Key") != "cx_test" || h.Get("Authorization") != "Bearer token" ||
+		h.Get("User-Agent") != "billing/1.2" || h.Get("Accept") != "application/json" {
+		t.Errorf("Unexpected request headers %v", h)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://example.com", "http://[::1"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("Expected %q to be rejected", baseURL)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for n, ceiling := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 8: time.Second, 70: time.Second} {
+		for range 100 {
+			if d := p.backoff(n); d < 0 || d > ceiling {
+				t.Fatalf("Attempt %d: delay %v outside [0, %v]", n, d, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Code is the stable error code of an error response. Codes are errors
+// themselves, so errors.Is(err, CodeRateLimited) tells whether err is an
+// *Error with that code.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// The error codes the service reports
+const (
+	CodeInvalidRequest      Code = "INVALID_REQUEST"
+	CodeInvalidBody         Code = "INVALID_BODY"
+	CodeMissingParameter    Code = "MISSING_PARAMETER"
+	CodeInvalidParameter    Code = "INVALID_PARAMETER"
+	CodeInvalidAmount       Code = "INVALID_AMOUNT"
+	CodeUnsupportedCurrency Code = "UNSUPPORTED_CURRENCY"
+	CodeBatchTooLarge       Code = "BATCH_TOO_LARGE"
+	CodeUnauthorized        Code = "UNAUTHORIZED"
+	CodeForbidden           Code = "FORBIDDEN"
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeSnapshotNotFound    Code = "SNAPSHOT_NOT_FOUND"
+	CodeMethodNotAllowed    Code = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable       Code = "NOT_ACCEPTABLE"
+	CodeUpgradeRequired     Code = "UPGRADE_REQUIRED"
+	CodeRateLimited         Code = "RATE_LIMITED"
+	CodeInternal            Code = "INTERNAL_ERROR"
+	CodeRatesStale          Code = "RATES_STALE"
+	CodeUnavailable         Code = "SERVICE_UNAVAILABLE"
+)
+
+// Error is an error response from the service. Errors of batch entries
+// carry only Code, Field and Detail.
+type Error struct {
+	// Status is the HTTP status code
+	Status int
+	// Code is empty when the response was not from the service, e.g. a
+	// proxy's 502
+	Code Code
+	// Field names the parameter at fault, if any
+	Field     string
+	Title     string
+	Detail    string
+	RequestID string
+	// RetryAfter is how long the response asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("currency service")
+	if e.Status != 0 {
+		b.WriteString(": " + strconv.Itoa(e.Status))
+	}
+	if e.Code != "" {
+		b.WriteString(" " + string(e.Code))
+	}
+	if e.Detail != "" {
+		b.WriteString(": " + e.Detail)
+	}
+	return b.String()
+}
+
+// Is matches a Code
+func (e *Error) Is(target error) bool {
+	code, ok := target.(Code)
+	return ok && code == e.Code
+}
+
+// maxErrorBody bounds how much of an error body is read
+const maxErrorBody = 64 << 10
+
+// responseError builds the *Error for an unsuccessful response
+func responseError(resp *http.Response) *Error {
+	e := &Error{
+		Status:     resp.StatusCode,
+		Title:      http.StatusText(resp.StatusCode),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	var problem struct {
+		Title     string `json:"title"`
+		Detail    string `json:"detail"`
+		Code      Code   `json:"code"`
+		Field     string `json:"field"`
+		RequestID string `json:"request_id"`
+		Error     string `json:"error"`
+	}
+	if json.Unmarshal(body, &problem) != nil {
+		e.Detail = strings.TrimSpace(string(body))
+		return e
+	}
+	e.Code, e.Field, e.RequestID = problem.Code, problem.Field, problem.RequestID
+	if problem.Title != "" {
+		e.Title = problem.Title
+	}
+	e.Detail = problem.Detail
+	if e.Detail == "" {
+		e.Detail = problem.Error
+	}
+	return e
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
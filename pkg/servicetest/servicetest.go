@@ -0,0 +1,250 @@
+// Package servicetest runs the currency service in process for the tests
+// of programs that call it. A Server serves the real HTTP API from an
+// httptest.Server, with rates the test chooses, failures and latency it
+// injects, and a record of every request it received:
+//
+//	srv := servicetest.New(t, servicetest.WithRates("USD", map[string]float64{"USD": 1, "EUR": 0.9}))
+//	srv.Inject(servicetest.Fault{Path: "/v1/exchange", Status: http.StatusServiceUnavailable, Times: 1})
+//
+//	// ... run the code under test against srv.URL ...
+//
+//	if calls := srv.RequestsTo("/v1/exchange"); len(calls) != 2 {
+//		t.Errorf("expected a retry, got %d calls", len(calls))
+//	}
+package servicetest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"currency_go_microservice/internal/httpx"
+	"currency_go_microservice/internal/logging"
+	"currency_go_microservice/internal/router"
+	"currency_go_microservice/internal/service"
+)
+
+// Server is an in-process currency service. The embedded httptest.Server
+// provides URL and Client; it is closed when the test ends.
+type Server struct {
+	*httptest.Server
+	service *service.CurrencyService
+
+	mu       sync.Mutex
+	faults   []*Fault
+	requests []*Request
+}
+
+// Option configures a Server
+type Option func(*options)
+
+type options struct {
+	base  string
+	rates map[string]float64
+}
+
+// WithRates starts the server with rates against base instead of the
+// built-in table
+func WithRates(base string, rates map[string]float64) Option {
+	return func(o *options) {
+		o.base, o.rates = base, rates
+	}
+}
+
+// New starts a Server for the duration of t. The snapshot admin routes
+// are enabled and the anomaly guard is not, so any rates can be set.
+func New(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Server{service: service.NewCurrencyService(service.WithAnomalyGuard(nil))}
+	if o.rates != nil {
+		if err := s.SetRates(o.base, o.rates); err != nil {
+			t.Fatalf("servicetest: %v", err)
+		}
+	}
+
+	rt := router.New(service.WriteError)
+	rt.Use(logging.RequestIDs, router.Recover(service.WriteError))
+	s.service.RegisterRoutes(rt, service.RouteOptions{SnapshotAdmin: true})
+	s.Server = httptest.NewServer(s.record(s.inject(rt)))
+	t.Cleanup(func() {
+		// Streams only end when told to, and Close waits for them
+		s.service.StopStreams()
+		s.Close()
+	})
+	return s
+}
+
+// SetRates installs rates against base as a new snapshot, which becomes
+// active. The base currency must have a rate of 1.
+func (s *Server) SetRates(base string, rates map[string]float64) error {
+	snapshot := service.NewRateSnapshot(base, rates)
+	snapshot.Source = "servicetest"
+	return s.service.InstallSnapshot(snapshot)
+}
+
+// Fault is a failure or delay injected into matching requests
+type Fault struct {
+	// Method matches requests with this method; empty matches all
+	Method string
+	// Path matches requests for this URL path, e.g. "/v1/exchange";
+	// empty matches all
+	Path string
+	// Latency delays the response, or the fault, by this long
+	Latency time.Duration
+	// Status, when set, replaces the service's response with an error
+	// response of this status and the code the service uses for it
+	Status int
+	// RetryAfter sets the Retry-After header of the error response
+	RetryAfter time.Duration
+	// Drop closes the connection without a response
+	Drop bool
+	// Times is how many requests the fault applies to; 0 means every
+	// request until Reset
+	Times int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && (f.Path == "" || f.Path == r.URL.Path)
+}
+
+// Inject adds a fault. Each request gets the first matching fault, in the
+// order they were injected.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// fault returns a copy of the fault for r, using up one of its times
+func (s *Server) fault(r *http.Request) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return *f, true
+	}
+	return Fault{}, false
+}
+
+// inject applies the faults before the service sees a request
+func (s *Server) inject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := s.fault(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if f.Latency > 0 {
+			timer := time.NewTimer(f.Latency)
+			select {
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		switch {
+		case f.Drop:
+			if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+				conn.Close()
+			}
+		case f.Status != 0:
+			if f.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second).Seconds())))
+			}
+			service.WriteError(w, r, f.Status, "Injected fault")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Request is a request the server received
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+	// Status is the response status, or 0 while the response is being
+	// written and for dropped connections
+	Status int
+	Time   time.Time
+}
+
+// record appends every request to the log before handling it and fills
+// in its status afterwards
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		req := &Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+			Time:   time.Now(),
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+
+		rec := httpx.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+		s.mu.Lock()
+		req.Status = rec.Status
+		s.mu.Unlock()
+	})
+}
+
+// Requests returns the requests received so far, oldest first
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]Request, len(s.requests))
+	for i, r := range s.requests {
+		requests[i] = *r
+	}
+	return requests
+}
+
+// RequestsTo returns the requests received for path
+func (s *Server) RequestsTo(path string) []Request {
+	var matching []Request
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+// Reset removes the injected faults and the recorded requests. The rates
+// stay as they are.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+	s.requests = nil
+}
@@ -0,0 +1,183 @@
+package servicetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"currency_go_microservice/pkg/client"
+)
+
+// newClient returns a client of srv that retries quickly
+func newClient(t *testing.T, srv *Server, attempts int) *client.Client {
+	t.Helper()
+	c, err := client.New(srv.URL, client.WithHTTPClient(srv.Client()),
+		client.WithRetry(client.RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRates(t *testing.T) {
+	srv := New(t, WithRates("usd", map[string]float64{"USD": 1, "EUR": 0.9}))
+	c := newClient(t, srv, 1)
+	ctx := context.Background()
+
+	conversion, err := c.Convert(ctx, "USD", "EUR", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversion.ConvertedAmount != 90 || conversion.Source != "servicetest" || conversion.Version != 2 {
+		t.Errorf("Expected the programmed rate, got %+v", conversion)
+	}
+	if _, err := c.Convert(ctx, "USD", "GBP", 1); !errors.Is(err, client.CodeUnsupportedCurrency) {
+		t.Errorf("Expected only the programmed currencies, got %v", err)
+	}
+
+	// Changes are not checked by the anomaly guard
+	if err := srv.SetRates("USD", map[string]float64{"USD": 1, "EUR": 9}); err != nil {
+		t.Fatal(err)
+	}
+	if conversion, _ := c.Convert(ctx, "USD", "EUR", 100); conversion == nil || conversion.ConvertedAmount != 900 {
+		t.Errorf("Expected the new rate, got %+v", conversion)
+	}
+
+	if err := srv.SetRates("USD", map[string]float64{"EUR": 0.9}); err == nil {
+		t.Errorf("Expected rates without the base to be rejected")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		faults   []Fault
+		attempts int
+		statuses []int
+		code     client.Code
+	}{
+		{"Fails once then recovers",
+			[]Fault{{Path: "/v1/exchange", Status: http.StatusServiceUnavailable, Times: 1}},
+			3, []int{503, 200}, ""},
+		{"Fails until reset",
+			[]Fault{{Status: http.StatusInternalServerError}},
+			3, []int{500, 500, 500}, client.CodeInternal},
+		{"Rate limited with Retry-After",
+			[]Fault{{Path: "/v1/exchange", Status: http.StatusTooManyRequests, RetryAfter: time.Hour}},
+			3, []int{429}, client.CodeRateLimited},
+		{"Other paths are unaffected",
+			[]Fault{{Path: "/v1/rates", Status: http.StatusServiceUnavailable}},
+			3, []int{200}, ""},
+		{"Other methods are unaffected",
+			[]Fault{{Method: http.MethodPost, Status: http.StatusServiceUnavailable}},
+			3, []int{200}, ""},
+		{"First matching fault wins",
+			[]Fault{{Path: "/v1/exchange", Status: http.StatusBadGateway, Times: 1}, {Status: http.StatusServiceUnavailable, Times: 1}},
+			3, []int{502, 503, 200}, ""},
+		{"Dropped connection",
+			[]Fault{{Drop: true, Times: 1}},
+			2, []int{0, 200}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(t)
+			for _, f := range tt.faults {
+				srv.Inject(f)
+			}
+			_, err := newClient(t, srv, tt.attempts).Convert(context.Background(), "USD", "EUR", 1)
+			if tt.code == "" && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if tt.code != "" && !errors.Is(err, tt.code) {
+				t.Errorf("Expected %s, got %v", tt.code, err)
+			}
+
+			requests := srv.Requests()
+			if len(requests) != len(tt.statuses) {
+				t.Fatalf("Expected %d requests, got %d", len(tt.statuses), len(requests))
+			}
+			for i, r := range requests {
+				if r.Status != tt.statuses[i] {
+					t.Errorf("Request %d: expected status %d, got %d", i, tt.statuses[i], r.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestLatency(t *testing.T) {
+	srv := New(t)
+	srv.Inject(Fault{Path: "/v1/rates", Latency: 50 * time.Millisecond})
+	c := newClient(t, srv, 1)
+
+	start := time.Now()
+	if _, err := c.Rates(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected at least 50ms of latency, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Rates(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the caller to time out, got %v", err)
+	}
+}
+
+func TestRequestRecording(t *testing.T) {
+	srv := New(t)
+	c, _ := client.New(srv.URL, client.WithAPIKey("cx_test"))
+	_, err := c.BatchConvert(context.Background(), []client.ConversionRequest{{From: "USD", To: "JPY", Amount: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Convert(context.Background(), "EUR", "USD", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := srv.RequestsTo("/v1/exchange/batch")
+	if len(batch) != 1 {
+		t.Fatalf("Expected one batch request, got %+v", srv.Requests())
+	}
+	var body struct {
+		Conversions []client.ConversionRequest `json:"conversions"`
+	}
+	if err := json.Unmarshal(batch[0].Body, &body); err != nil || len(body.Conversions) != 1 || body.Conversions[0].To != "JPY" {
+		t.Errorf("Expected the batch body to be recorded, got %s", batch[0].Body)
+	}
+	if batch[0].Method != http.MethodPost || batch[0].Header.Get("X-API-Key") != "cx_test" || batch[0].Status != http.StatusOK {
+		t.Errorf("Unexpected batch request %+v", batch[0])
+	}
+
+	exchange := srv.RequestsTo("/v1/exchange")
+	if len(exchange) != 1 || exchange[0].Query.Get("from") != "EUR" || exchange[0].Query.Get("amount") != "5" {
+		t.Errorf("Expected the exchange query to be recorded, got %+v", exchange)
+	}
+
+	srv.Inject(Fault{Status: http.StatusServiceUnavailable})
+	srv.Reset()
+	if len(srv.Requests()) != 0 {
+		t.Errorf("Expected Reset to clear the requests")
+	}
+	if _, err := c.Rates(context.Background()); err != nil {
+		t.Errorf("Expected Reset to clear the faults, got %v", err)
+	}
+}
+
+func TestStreamsEndWithTest(t *testing.T) {
+	srv := New(t)
+	resp, err := srv.Client().Get(srv.URL + "/v1/rates/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	// The body is left open, so the server's cleanup has to end the stream
+	// before it can close
+}
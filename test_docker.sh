@@ -43,11 +43,11 @@ curl -f http://localhost:8080/health || echo "Health check failed"
 
 # Test rates endpoint
 echo "=== Testing Rates Endpoint ==="
-curl -f http://localhost:8080/rates || echo "Rates check failed"
+curl -f http://localhost:8080/v1/rates || echo "Rates check failed"
 
 # Test exchange endpoint
 echo "=== Testing Exchange Endpoint ==="
-curl -f "http://localhost:8080/exchange?from=USD&to=EUR&amount=100" || echo "Exchange check failed"
+curl -f "http://localhost:8080/v1/exchange?from=USD&to=EUR&amount=100" || echo "Exchange check failed"
 
 # Run integration tests
 echo "=== Running Integration Tests ==="